@@ -0,0 +1,402 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"github.com/skybi/nuntius/internal/dedup"
+	"github.com/skybi/nuntius/internal/feeder"
+	"github.com/skybi/nuntius/internal/metar"
+	"github.com/skybi/nuntius/internal/queue"
+	"github.com/skybi/nuntius/internal/set"
+	"github.com/skybi/nuntius/internal/source"
+	"github.com/skybi/nuntius/internal/taf"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+const usage = `usage: worker [command]
+
+Without a command, the worker is started. Commands must only be run while the worker is stopped.
+
+commands:
+  queue <metar|taf> stats                      print the amount of queued reports
+  queue <metar|taf> export                     print the queued reports as JSON lines
+  queue <metar|taf> import [file]              queue the reports of JSON lines read from file or stdin
+  queue <metar|taf> purge <filter>             remove the queued reports matching the filter
+  state <metar|taf> stats                      print the amount of reports per state file
+  state <metar|taf> export                     print the reports of the state files as JSON lines
+  state <metar|taf> import [file]              add the reports of JSON lines read from file or stdin to the state files
+  state <metar|taf> purge <filter>             remove the reports matching the filter from the state files
+  dead-letters <metar|taf> list                print the dead letters of a feeder as JSON lines
+  dead-letters <metar|taf> replay              move the dead letters of a feeder back into its queue
+
+filters:
+  -station <ICAO>                              reports issued for the given station
+  -pattern <regexp>                            reports matching the given regular expression`
+
+// errUsage is returned when a command was called with invalid arguments
+var errUsage = errors.New("invalid usage")
+
+// stateFilePatterns are the names of the state files the sources persist into the data directory of a report kind
+var stateFilePatterns = []string{"cycle-state-*", "awc-state"}
+
+// queueLine represents a queued report exported as a JSON line
+type queueLine struct {
+	Report    string `json:"report"`
+	Attempts  int    `json:"attempts,omitempty"`
+	LastError string `json:"last_error,omitempty"`
+}
+
+// stateLine represents a report of a state file exported as a JSON line
+type stateLine struct {
+	File   string `json:"file"`
+	Report string `json:"report"`
+}
+
+// runCommand runs a maintenance command operating on the persisted data of the feeders and sources
+func runCommand(args []string, options feeder.Options) error {
+	if len(args) < 3 {
+		return errUsage
+	}
+	kind, action, rest := args[1], args[2], args[3:]
+	dataDir, reportFeeder, err := newOfflineFeeder(kind, options)
+	if err != nil {
+		return err
+	}
+
+	switch args[0] {
+	case "queue":
+		err = runQueue(reportFeeder, action, rest)
+	case "state":
+		err = runState(dataDir, action, rest)
+	case "dead-letters":
+		err = runDeadLetters(reportFeeder, action)
+	default:
+		err = fmt.Errorf("%w: unknown command '%s'", errUsage, args[0])
+	}
+	if errors.Is(err, queue.ErrLocked) {
+		return fmt.Errorf("%w; stop the worker before running this command", err)
+	}
+	return err
+}
+
+// newOfflineFeeder creates a feeder of the given kind that is only used to access its persisted data and returns it
+// together with its data directory
+func newOfflineFeeder(kind string, options feeder.Options) (string, *feeder.Feeder, error) {
+	switch kind {
+	case "metar":
+		return metar.DataDir, metar.NewFeeder(nil, options), nil
+	case "taf":
+		return taf.DataDir, taf.NewFeeder(nil, options), nil
+	default:
+		return "", nil, fmt.Errorf("unknown report kind '%s'", kind)
+	}
+}
+
+// runQueue inspects or modifies the queue of a feeder
+func runQueue(reportFeeder *feeder.Feeder, action string, args []string) error {
+	switch action {
+	case "stats":
+		return reportFeeder.Inspect(func(wal *queue.WAL[string]) error {
+			retried := 0
+			for _, entry := range wal.PopN(wal.Size()) {
+				if entry.Attempts > 0 {
+					retried++
+				}
+			}
+			letters, err := reportFeeder.DeadLetters()
+			if err != nil {
+				return err
+			}
+			fmt.Printf("queued: %d\nretried: %d\ndead letters: %d\n", wal.InFlight(), retried, len(letters))
+			return nil
+		})
+	case "export":
+		return reportFeeder.Inspect(func(wal *queue.WAL[string]) error {
+			writer := bufio.NewWriter(os.Stdout)
+			encoder := json.NewEncoder(writer)
+			for _, entry := range wal.PopN(wal.Size()) {
+				err := encoder.Encode(&queueLine{
+					Report:    entry.Value,
+					Attempts:  entry.Attempts,
+					LastError: entry.LastError,
+				})
+				if err != nil {
+					return err
+				}
+			}
+			return writer.Flush()
+		})
+	case "import":
+		var entries []*queue.Entry[string]
+		err := readLines(args, func(line []byte) error {
+			parsed := new(queueLine)
+			if err := json.Unmarshal(line, parsed); err != nil {
+				return err
+			}
+			if parsed.Report != "" {
+				entries = append(entries, &queue.Entry[string]{
+					Value:     parsed.Report,
+					Attempts:  parsed.Attempts,
+					LastError: parsed.LastError,
+				})
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+		return reportFeeder.Inspect(func(wal *queue.WAL[string]) error {
+			if _, err := wal.PushEntries(entries...); err != nil {
+				return err
+			}
+			fmt.Fprintf(os.Stderr, "imported %d reports\n", len(entries))
+			return nil
+		})
+	case "purge":
+		matches, err := newFilter(args)
+		if err != nil {
+			return err
+		}
+		return reportFeeder.Inspect(func(wal *queue.WAL[string]) error {
+			var purge []*queue.Entry[string]
+			for _, entry := range wal.PopN(wal.Size()) {
+				if matches(entry.Value) {
+					purge = append(purge, entry)
+				}
+			}
+			if err := wal.Ack(purge...); err != nil {
+				return err
+			}
+			fmt.Fprintf(os.Stderr, "purged %d reports\n", len(purge))
+			return nil
+		})
+	default:
+		return fmt.Errorf("%w: unknown queue action '%s'", errUsage, action)
+	}
+}
+
+// runState inspects or modifies the state files of the sources of a report kind
+func runState(dataDir, action string, args []string) error {
+	switch action {
+	case "stats":
+		return eachStateFile(dataDir, func(name string, reports *set.HashSet[string]) (bool, error) {
+			fmt.Printf("%s: %d\n", name, reports.Size())
+			return false, nil
+		})
+	case "export":
+		writer := bufio.NewWriter(os.Stdout)
+		encoder := json.NewEncoder(writer)
+		err := eachStateFile(dataDir, func(name string, reports *set.HashSet[string]) (bool, error) {
+			values := reports.ToSlice()
+			sort.Strings(values)
+			for _, report := range values {
+				if err := encoder.Encode(&stateLine{File: name, Report: report}); err != nil {
+					return false, err
+				}
+			}
+			return false, nil
+		})
+		if err != nil {
+			return err
+		}
+		return writer.Flush()
+	case "import":
+		imported := make(map[string][]string)
+		err := readLines(args, func(line []byte) error {
+			parsed := new(stateLine)
+			if err := json.Unmarshal(line, parsed); err != nil {
+				return err
+			}
+			if !isStateFile(parsed.File) {
+				return fmt.Errorf("invalid state file '%s'", parsed.File)
+			}
+			imported[parsed.File] = append(imported[parsed.File], parsed.Report)
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+		for name, values := range imported {
+			path := filepath.Join(dataDir, name)
+			reports, err := source.LoadState(path)
+			if err != nil {
+				return err
+			}
+			for _, report := range values {
+				reports.Add(report)
+			}
+			if err := source.SaveState(path, reports); err != nil {
+				return err
+			}
+			fmt.Fprintf(os.Stderr, "%s: imported %d reports\n", name, len(values))
+		}
+		return nil
+	case "purge":
+		matches, err := newFilter(args)
+		if err != nil {
+			return err
+		}
+		return eachStateFile(dataDir, func(name string, reports *set.HashSet[string]) (bool, error) {
+			purged := 0
+			for _, report := range reports.ToSlice() {
+				if matches(report) {
+					reports.Remove(report)
+					purged++
+				}
+			}
+			fmt.Fprintf(os.Stderr, "%s: purged %d reports\n", name, purged)
+			return purged > 0, nil
+		})
+	default:
+		return fmt.Errorf("%w: unknown state action '%s'", errUsage, action)
+	}
+}
+
+// eachStateFile loads every state file inside dataDir and passes it to fn, saving it again if fn reports that it
+// modified it
+func eachStateFile(dataDir string, fn func(name string, reports *set.HashSet[string]) (bool, error)) error {
+	var names []string
+	for _, pattern := range stateFilePatterns {
+		matches, err := filepath.Glob(filepath.Join(dataDir, pattern))
+		if err != nil {
+			return err
+		}
+		for _, match := range matches {
+			names = append(names, filepath.Base(match))
+		}
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		path := filepath.Join(dataDir, name)
+		reports, err := source.LoadState(path)
+		if err != nil {
+			return fmt.Errorf("state file '%s': %w", name, err)
+		}
+		modified, err := fn(name, reports)
+		if err != nil {
+			return err
+		}
+		if modified {
+			if err := source.SaveState(path, reports); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// isStateFile reports whether name is the name of a state file inside the data directory
+func isStateFile(name string) bool {
+	if name == "" || filepath.Base(name) != name {
+		return false
+	}
+	for _, pattern := range stateFilePatterns {
+		if matched, _ := filepath.Match(pattern, name); matched {
+			return true
+		}
+	}
+	return false
+}
+
+// readLines calls fn for every non-empty line of the file given as the only argument or stdin if there is none
+func readLines(args []string, fn func(line []byte) error) error {
+	var reader io.Reader = os.Stdin
+	switch len(args) {
+	case 0:
+	case 1:
+		inputFile, err := os.Open(args[0])
+		if err != nil {
+			return err
+		}
+		defer inputFile.Close()
+		reader = inputFile
+	default:
+		return errUsage
+	}
+
+	scanner := bufio.NewScanner(reader)
+	scanner.Buffer(nil, 1<<20)
+	for line := 1; scanner.Scan(); line++ {
+		data := scanner.Bytes()
+		if len(strings.TrimSpace(string(data))) == 0 {
+			continue
+		}
+		if err := fn(data); err != nil {
+			return fmt.Errorf("line %d: %w", line, err)
+		}
+	}
+	return scanner.Err()
+}
+
+// newFilter creates the function selecting the reports a purge command removes out of its arguments
+func newFilter(args []string) (func(report string) bool, error) {
+	flags := flag.NewFlagSet("purge", flag.ContinueOnError)
+	flags.SetOutput(io.Discard)
+	station := flags.String("station", "", "")
+	pattern := flags.String("pattern", "", "")
+	if err := flags.Parse(args); err != nil {
+		return nil, fmt.Errorf("%w: %v", errUsage, err)
+	}
+
+	switch {
+	case *station != "" && *pattern == "":
+		code := strings.ToUpper(*station)
+		return func(report string) bool {
+			return stationOf(report) == code
+		}, nil
+	case *pattern != "" && *station == "":
+		expression, err := regexp.Compile(*pattern)
+		if err != nil {
+			return nil, err
+		}
+		return expression.MatchString, nil
+	default:
+		return nil, fmt.Errorf("%w: exactly one of -station and -pattern is required", errUsage)
+	}
+}
+
+// stationOf returns the ICAO code of the station a report was issued for
+func stationOf(report string) string {
+	for _, field := range strings.Fields(dedup.Identity(report)) {
+		if field != "COR" && field != "AMD" && field != "CNL" {
+			return field
+		}
+	}
+	return ""
+}
+
+// runDeadLetters lists or replays the dead letters of a feeder
+func runDeadLetters(reportFeeder *feeder.Feeder, action string) error {
+	switch action {
+	case "list":
+		letters, err := reportFeeder.DeadLetters()
+		if err != nil {
+			return err
+		}
+		encoder := json.NewEncoder(os.Stdout)
+		for _, letter := range letters {
+			if err := encoder.Encode(letter); err != nil {
+				return err
+			}
+		}
+		return nil
+	case "replay":
+		amount, err := reportFeeder.ReplayDeadLetters()
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(os.Stderr, "replayed %d dead letters\n", amount)
+		return nil
+	default:
+		return fmt.Errorf("%w: unknown dead-letters action '%s'", errUsage, action)
+	}
+}
@@ -1,14 +1,30 @@
 package main
 
 import (
+	"errors"
 	"fmt"
 	"github.com/rs/zerolog"
 	"github.com/rs/zerolog/log"
+	"github.com/skybi/nuntius/internal/backoff"
+	"github.com/skybi/nuntius/internal/bulletin"
 	"github.com/skybi/nuntius/internal/client"
 	"github.com/skybi/nuntius/internal/config"
+	"github.com/skybi/nuntius/internal/feeder"
+	"github.com/skybi/nuntius/internal/iwxxm"
 	"github.com/skybi/nuntius/internal/metar"
+	"github.com/skybi/nuntius/internal/queue"
+	"github.com/skybi/nuntius/internal/sink"
+	"github.com/skybi/nuntius/internal/source"
+	"github.com/skybi/nuntius/internal/source/awc"
+	"github.com/skybi/nuntius/internal/source/noaa"
+	"github.com/skybi/nuntius/internal/source/push"
+	"github.com/skybi/nuntius/internal/source/spool"
+	"github.com/skybi/nuntius/internal/taf"
+	"net/http"
 	"os"
 	"os/signal"
+	"path/filepath"
+	"strings"
 	"time"
 )
 
@@ -32,6 +48,41 @@ func main() {
 	}
 	log.Debug().Str("config", fmt.Sprintf("%+v", cfg)).Msg("")
 
+	// Configure the feeders shared by all report kinds
+	overflow, err := queue.ParseOverflowPolicy(cfg.QueueOverflow)
+	if err != nil {
+		log.Fatal().Err(err).Msg("invalid queue overflow policy")
+	}
+	order, err := queue.ParseOrder(cfg.QueueOrder)
+	if err != nil {
+		log.Fatal().Err(err).Msg("invalid queue order")
+	}
+	feederOptions := feeder.Options{
+		BatchSize:   500,
+		Interval:    time.Second,
+		DedupExpiry: cfg.DedupExpiry,
+		MaxAttempts: cfg.QueueMaxAttempts,
+		MaxAge:      cfg.MaxReportAge,
+		Queue: queue.Options{
+			SegmentSize:  queue.DefaultSegmentSize,
+			Capacity:     cfg.QueueCapacity,
+			Overflow:     overflow,
+			Order:        order,
+			BacklogShare: cfg.QueueBacklogShare,
+		},
+	}
+
+	// Run a maintenance command instead of the worker if one was given
+	if len(os.Args) > 1 {
+		if err := runCommand(os.Args[1:], feederOptions); err != nil {
+			if errors.Is(err, errUsage) {
+				fmt.Fprintln(os.Stderr, usage)
+			}
+			log.Fatal().Err(err).Msg("command failed")
+		}
+		return
+	}
+
 	// Initialize the API client
 	apiClient := client.New(cfg.APIAddress, cfg.APIKey)
 	keyInfo, err := apiClient.GetKeyInfo()
@@ -53,15 +104,54 @@ func main() {
 		log.Warn().Msg("METAR feeding disabled due to lack of required key capability")
 	}
 
+	// Check if the key may feed TAFs
+	if cfg.FeedTAFs && keyInfo.Capabilities&client.CapabilityFeedTAFs == 0 {
+		cfg.FeedTAFs = false
+		log.Warn().Msg("TAF feeding disabled due to lack of required key capability")
+	}
+
 	// Abort if no feeding is enabled
-	if !cfg.FeedMETARs {
+	if !cfg.FeedMETARs && !cfg.FeedTAFs {
 		log.Fatal().Msg("aborting due to disabled feeding")
 	}
 
+	// Create the FTP connection pool shared by all cycle sources
+	ftpPool := noaa.NewFTPPool(noaa.FTPConfig{
+		Address:     cfg.FTPAddress,
+		User:        cfg.FTPUser,
+		Password:    cfg.FTPPassword,
+		BasePath:    cfg.FTPBasePath,
+		DialTimeout: cfg.FTPDialTimeout,
+	}, cfg.FTPMaxConns, cfg.FTPIdleTimeout, backoff.Backoff{
+		Min:    cfg.FTPBackoffMin,
+		Max:    cfg.FTPBackoffMax,
+		Factor: 2,
+		Jitter: 0.5,
+	})
+	defer ftpPool.Close()
+
+	// Poll the cycles receiving new reports more often than the stale ones
+	cycleSchedule := noaa.Schedule{
+		ActiveInterval: cfg.CycleActiveInterval,
+		PeakInterval:   cfg.CyclePeakInterval,
+		StaleInterval:  cfg.CycleStaleInterval,
+		Jitter:         0.2,
+	}
+
+	// Create the push ingest server if necessary; its endpoints are registered by the feeding pipelines
+	var ingestServer *push.Server
+	if cfg.IngestAddress != "" {
+		ingestServer = push.NewServer(cfg.IngestAddress, cfg.IngestKey)
+	}
+
 	// Start feeding METARs if necessary
 	if cfg.FeedMETARs {
 		log.Info().Msg("starting the METAR feeder...")
-		feeder := metar.NewFeeder(apiClient, 500, time.Second)
+		metarSink, err := newSink(cfg.METARSinks, cfg.METARSinkFile, sink.NewMETARAPI(apiClient))
+		if err != nil {
+			log.Fatal().Err(err).Msg("could not create the METAR sink")
+		}
+		feeder := metar.NewFeeder(metarSink, feederOptions)
 		if err := feeder.Start(); err != nil {
 			log.Fatal().Err(err).Msg("could not start the METAR feeder")
 		}
@@ -71,9 +161,147 @@ func main() {
 			}
 		}()
 
-		workers := metar.InitWorkers(feeder)
-		workers.Start()
-		defer workers.Stop()
+		transport, err := newCycleTransport(cfg, ftpPool, cfg.METARChangeDetection)
+		if err != nil {
+			log.Fatal().Err(err).Msg("could not create the METAR cycle transport")
+		}
+		cycles := noaa.NewCycleSource(transport, noaa.CycleSourceConfig{
+			Name:        "noaa-metar-cycles",
+			Location:    noaa.METARCyclesLocation,
+			FilePattern: cfg.CycleFilePattern,
+			StateDir:    metar.DataDir,
+			Format: noaa.Format{
+				Extract:   metar.Extract,
+				Normalize: metar.Fix,
+				Separator: []byte("\n"),
+			},
+			Schedule: cycleSchedule,
+		})
+		if err := cycles.Start(feeder.Receive); err != nil {
+			log.Fatal().Err(err).Msg("could not start the METAR cycle source")
+		}
+		defer cycles.Stop()
+
+		// Start the AviationWeather.gov cache file source if necessary
+		if cfg.AWCEnabled {
+			format, err := awc.ParseFormat(cfg.AWCFormat)
+			if err != nil {
+				log.Fatal().Err(err).Msg("could not parse the cache file format")
+			}
+			url := cfg.AWCURL
+			if url == "" {
+				url = awc.DefaultURL(format)
+			}
+			cache := awc.New(url, format, &http.Client{
+				Timeout: cfg.HTTPTimeout,
+			}, filepath.Join(metar.DataDir, "awc-state"), cfg.AWCInterval)
+			if err := cache.Start(feeder.Receive); err != nil {
+				log.Fatal().Err(err).Msg("could not start the cache file source")
+			}
+			defer cache.Stop()
+		}
+
+		// Start the spool directory source if necessary
+		if cfg.SpoolDir != "" {
+			var extract source.ExtractFunc
+			switch strings.ToLower(strings.TrimSpace(cfg.SpoolFormat)) {
+			case "text":
+				extract = metar.Extract
+			case "bulletin":
+				extract = bulletin.Extract
+			case "iwxxm":
+				extract = iwxxm.Extract
+			default:
+				log.Fatal().Str("format", cfg.SpoolFormat).Msg("unknown spool file format")
+			}
+			spoolSource := spool.New(cfg.SpoolDir, extract, cfg.SpoolInterval, cfg.SpoolSettle)
+			if err := spoolSource.Start(feeder.Receive); err != nil {
+				log.Fatal().Err(err).Msg("could not start the spool directory source")
+			}
+			defer spoolSource.Stop()
+		}
+
+		// Accept METARs pushed to the ingest server if necessary
+		if ingestServer != nil {
+			endpoint := ingestServer.Endpoint("metars", "text/plain", metar.Extract)
+			if err := endpoint.Start(feeder.Receive); err != nil {
+				log.Fatal().Err(err).Msg("could not start the METAR ingest endpoint")
+			}
+			defer endpoint.Stop()
+
+			bulletinEndpoint := ingestServer.Endpoint("bulletins", "text/plain", bulletin.Extract)
+			if err := bulletinEndpoint.Start(feeder.Receive); err != nil {
+				log.Fatal().Err(err).Msg("could not start the bulletin ingest endpoint")
+			}
+			defer bulletinEndpoint.Stop()
+
+			iwxxmEndpoint := ingestServer.Endpoint("iwxxm", "application/xml", iwxxm.Extract)
+			if err := iwxxmEndpoint.Start(feeder.Receive); err != nil {
+				log.Fatal().Err(err).Msg("could not start the IWXXM ingest endpoint")
+			}
+			defer iwxxmEndpoint.Stop()
+		}
+	}
+
+	// Start feeding TAFs if necessary
+	if cfg.FeedTAFs {
+		log.Info().Msg("starting the TAF feeder...")
+		tafSink, err := newSink(cfg.TAFSinks, cfg.TAFSinkFile, sink.NewTAFAPI(apiClient))
+		if err != nil {
+			log.Fatal().Err(err).Msg("could not create the TAF sink")
+		}
+		feeder := taf.NewFeeder(tafSink, feederOptions)
+		if err := feeder.Start(); err != nil {
+			log.Fatal().Err(err).Msg("could not start the TAF feeder")
+		}
+		defer func() {
+			if err := feeder.Stop(); err != nil {
+				log.Error().Err(err).Msg("could not gracefully shut down the TAF feeder")
+			}
+		}()
+
+		transport, err := newCycleTransport(cfg, ftpPool, cfg.TAFChangeDetection)
+		if err != nil {
+			log.Fatal().Err(err).Msg("could not create the TAF cycle transport")
+		}
+		cycles := noaa.NewCycleSource(transport, noaa.CycleSourceConfig{
+			Name:        "noaa-taf-cycles",
+			Location:    noaa.TAFCyclesLocation,
+			FilePattern: cfg.CycleFilePattern,
+			StateDir:    taf.DataDir,
+			Format: noaa.Format{
+				Extract:   taf.Extract,
+				Normalize: taf.Fix,
+				Separator: []byte("\n\n"),
+			},
+			Schedule: cycleSchedule,
+		})
+		if err := cycles.Start(feeder.Receive); err != nil {
+			log.Fatal().Err(err).Msg("could not start the TAF cycle source")
+		}
+		defer cycles.Stop()
+
+		// Accept TAFs pushed to the ingest server if necessary
+		if ingestServer != nil {
+			endpoint := ingestServer.Endpoint("tafs", "text/plain", taf.Extract)
+			if err := endpoint.Start(feeder.Receive); err != nil {
+				log.Fatal().Err(err).Msg("could not start the TAF ingest endpoint")
+			}
+			defer endpoint.Stop()
+		}
+	}
+
+	// Start the push ingest server if necessary
+	if ingestServer != nil {
+		log.Info().Str("address", cfg.IngestAddress).Msg("starting the ingest server...")
+		if err := ingestServer.Start(); err != nil {
+			log.Fatal().Err(err).Msg("could not start the ingest server")
+		}
+		defer func() {
+			if err := ingestServer.Stop(); err != nil {
+				log.Error().Err(err).Msg("could not gracefully shut down the ingest server")
+			}
+		}()
 	}
 
 	// Wait for the application to be terminated
@@ -81,3 +309,45 @@ func main() {
 	signal.Notify(shutdown, os.Interrupt)
 	<-shutdown
 }
+
+// newSink creates the sink reports are fed into according to the configured sink names
+func newSink(names []string, filePath string, apiSink sink.Sink) (sink.Sink, error) {
+	sinks := make([]sink.Sink, 0, len(names))
+	for _, name := range names {
+		switch strings.ToLower(strings.TrimSpace(name)) {
+		case "api":
+			sinks = append(sinks, apiSink)
+		case "file":
+			sinks = append(sinks, sink.NewFile(filePath))
+		default:
+			return nil, fmt.Errorf("unknown sink '%s'", name)
+		}
+	}
+
+	switch len(sinks) {
+	case 0:
+		return nil, errors.New("no sink configured")
+	case 1:
+		return sinks[0], nil
+	default:
+		return sink.NewMulti(sinks...), nil
+	}
+}
+
+// newCycleTransport creates the transport used to access the cycle files according to the configuration
+func newCycleTransport(cfg *config.Config, ftpPool *noaa.FTPPool, rawChangeDetection string) (noaa.Transport, error) {
+	switch strings.ToLower(strings.TrimSpace(cfg.CycleTransport)) {
+	case "ftp":
+		changeDetection, err := noaa.ParseChangeDetection(rawChangeDetection)
+		if err != nil {
+			return nil, err
+		}
+		return noaa.NewFTPTransport(ftpPool, changeDetection), nil
+	case "http", "https":
+		return noaa.NewHTTPTransport(cfg.HTTPBaseURL, &http.Client{
+			Timeout: cfg.HTTPTimeout,
+		}), nil
+	default:
+		return nil, fmt.Errorf("unknown cycle transport '%s'", cfg.CycleTransport)
+	}
+}
@@ -0,0 +1,37 @@
+package backoff
+
+import (
+	"math"
+	"math/rand"
+	"time"
+)
+
+// Backoff calculates exponentially growing delays with random jitter between retries
+type Backoff struct {
+	Min    time.Duration
+	Max    time.Duration
+	Factor float64
+
+	// Jitter is the fraction (0-1) of a delay that may randomly be subtracted from it
+	Jitter float64
+}
+
+// Delay returns the delay to wait before the given retry attempt (starting at 1)
+func (backoff Backoff) Delay(attempt int) time.Duration {
+	if attempt < 1 {
+		attempt = 1
+	}
+	factor := backoff.Factor
+	if factor < 1 {
+		factor = 2
+	}
+
+	delay := float64(backoff.Min) * math.Pow(factor, float64(attempt-1))
+	if max := float64(backoff.Max); backoff.Max > 0 && delay > max {
+		delay = max
+	}
+	if backoff.Jitter > 0 {
+		delay -= delay * backoff.Jitter * rand.Float64()
+	}
+	return time.Duration(delay)
+}
@@ -0,0 +1,157 @@
+package bulletin
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+const (
+	KeywordMETAR = "METAR"
+	KeywordSPECI = "SPECI"
+)
+
+var (
+	headingRegex = regexp.MustCompile(`^([A-Z]{4}\d{2})\s+([A-Z]{4})\s+(\d{6})(?:\s+((?:RR|CC|AA)[A-Z]))?$`)
+	timeRegex    = regexp.MustCompile(`^\d{6}Z$`)
+
+	// ErrNoHeading is returned if a bulletin does not contain a valid abbreviated heading
+	ErrNoHeading = errors.New("bulletin does not contain an abbreviated heading")
+)
+
+// Heading represents the abbreviated heading of a WMO bulletin (T1T2A1A2ii CCCC YYGGgg [BBB])
+type Heading struct {
+	// Designator contains the data type designators and the bulletin number, e.g. SAUK31
+	Designator string
+
+	// Originator is the ICAO location indicator of the originating centre
+	Originator string
+
+	// Time is the day of month, hour and minute of the bulletin (YYGGgg)
+	Time string
+
+	// Indicator is the optional RRx (delayed), CCx (corrected) or AAx (amended) indicator
+	Indicator string
+}
+
+// IsCorrection returns whether the bulletin corrects a previously sent one
+func (heading *Heading) IsCorrection() bool {
+	return strings.HasPrefix(heading.Indicator, "CC")
+}
+
+// IsDelayed returns whether the bulletin was sent delayed
+func (heading *Heading) IsDelayed() bool {
+	return strings.HasPrefix(heading.Indicator, "RR")
+}
+
+// Report represents a single report contained in a bulletin
+type Report struct {
+	// Keyword is either KeywordMETAR or KeywordSPECI
+	Keyword    string
+	Correction bool
+
+	// Raw is the report without its keyword, laid out like the ones in the NOAA cycle files (station first)
+	Raw string
+}
+
+// Bulletin represents a parsed METAR/SPECI bulletin
+type Bulletin struct {
+	Heading *Heading
+	Reports []*Report
+}
+
+// Parse parses a single METAR or SPECI bulletin.
+// The reports inherit the collective METAR/SPECI keyword and, if they lack their own, the time of the heading.
+func Parse(text string) (*Bulletin, error) {
+	lines := splitLines(text)
+
+	// Find the abbreviated heading; the starting line (ZCZC or a channel sequence number) may precede it
+	headingIndex := -1
+	var heading *Heading
+	for i, line := range lines {
+		if match := headingRegex.FindStringSubmatch(line); match != nil {
+			heading = &Heading{
+				Designator: match[1],
+				Originator: match[2],
+				Time:       match[3],
+				Indicator:  match[4],
+			}
+			headingIndex = i
+			break
+		}
+	}
+	if heading == nil {
+		return nil, ErrNoHeading
+	}
+
+	keyword := ""
+	switch heading.Designator[:2] {
+	case "SA":
+		keyword = KeywordMETAR
+	case "SP":
+		keyword = KeywordSPECI
+	default:
+		return nil, fmt.Errorf("bulletin of type '%s' does not contain METARs or SPECIs", heading.Designator[:2])
+	}
+
+	// Reports may span multiple lines and are terminated by '='
+	var body []string
+	for _, line := range lines[headingIndex+1:] {
+		if line == "NNNN" {
+			break
+		}
+		body = append(body, line)
+	}
+
+	bulletin := &Bulletin{
+		Heading: heading,
+	}
+	for _, segment := range strings.Split(strings.Join(body, " "), "=") {
+		fields := strings.Fields(segment)
+
+		// A keyword at the beginning of a segment applies to it and, as a collective keyword, to all following ones
+		correction := heading.IsCorrection()
+		if len(fields) > 0 && (fields[0] == KeywordMETAR || fields[0] == KeywordSPECI) {
+			keyword = fields[0]
+			fields = fields[1:]
+		}
+		if len(fields) > 0 && fields[0] == "COR" {
+			correction = true
+			fields = fields[1:]
+		}
+
+		// Skip empty segments and NIL reports of stations that did not report
+		if len(fields) == 0 || (len(fields) <= 3 && fields[len(fields)-1] == "NIL") {
+			continue
+		}
+
+		// Reports without their own time group inherit the one of the heading
+		if len(fields) < 2 || !timeRegex.MatchString(fields[1]) {
+			fields = append([]string{fields[0], heading.Time + "Z"}, fields[1:]...)
+		}
+
+		// Corrected reports carry COR right after their time group
+		if correction && (len(fields) < 3 || fields[2] != "COR") {
+			fields = append(fields[:2], append([]string{"COR"}, fields[2:]...)...)
+		}
+
+		bulletin.Reports = append(bulletin.Reports, &Report{
+			Keyword:    keyword,
+			Correction: correction,
+			Raw:        strings.Join(fields, " "),
+		})
+	}
+	return bulletin, nil
+}
+
+// splitLines splits a bulletin into trimmed lines, removing the SOH and ETX transmission control characters
+func splitLines(text string) []string {
+	text = strings.NewReplacer("\x01", "\n", "\x03", "\n", "\r", "").Replace(text)
+	rawLines := strings.Split(text, "\n")
+	lines := make([]string, 0, len(rawLines))
+	for _, line := range rawLines {
+		lines = append(lines, strings.TrimSpace(line))
+	}
+	return lines
+}
@@ -0,0 +1,163 @@
+package bulletin
+
+import (
+	"bufio"
+	"errors"
+	"github.com/skybi/nuntius/internal/source"
+	"sort"
+	"strings"
+	"testing"
+)
+
+// collective is a METAR collective as distributed over the GTS, including a report spanning two lines and a station
+// that did not report
+const collective = "\x01\r\r\n" +
+	"123\r\r\n" +
+	"SAUK31 EGGY 181220\r\r\n" +
+	"METAR EGLL 181220Z 24012KT 9999 FEW030 15/08 Q1013 NOSIG=\r\r\n" +
+	"EGKK 24010KT 9999\r\r\n" +
+	"      SCT025 14/07 Q1013=\r\r\n" +
+	"EGSS NIL=\r\r\n" +
+	"\x03"
+
+func reportTexts(bulletin *Bulletin) []string {
+	texts := make([]string, 0, len(bulletin.Reports))
+	for _, report := range bulletin.Reports {
+		texts = append(texts, source.WithKeyword(report.Keyword, report.Raw))
+	}
+	return texts
+}
+
+func assertTexts(t *testing.T, got []string, want ...string) {
+	t.Helper()
+	if strings.Join(got, "|") != strings.Join(want, "|") {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
+
+func TestParseCollective(t *testing.T) {
+	bulletin, err := Parse(collective)
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+
+	heading := bulletin.Heading
+	if heading.Designator != "SAUK31" || heading.Originator != "EGGY" || heading.Time != "181220" || heading.Indicator != "" {
+		t.Errorf("heading = %+v", heading)
+	}
+	if heading.IsCorrection() || heading.IsDelayed() {
+		t.Errorf("heading without indicator is a correction or delayed")
+	}
+	assertTexts(t, reportTexts(bulletin),
+		"EGLL 181220Z 24012KT 9999 FEW030 15/08 Q1013 NOSIG",
+		"EGKK 181220Z 24010KT 9999 SCT025 14/07 Q1013",
+	)
+	for _, report := range bulletin.Reports {
+		if report.Keyword != KeywordMETAR || report.Correction {
+			t.Errorf("report %q has keyword %s and correction %v", report.Raw, report.Keyword, report.Correction)
+		}
+	}
+}
+
+func TestParseIndicators(t *testing.T) {
+	tests := []struct {
+		name           string
+		text           string
+		wantCorrection bool
+		wantDelayed    bool
+		want           []string
+	}{
+		{
+			name:           "corrected bulletin",
+			text:           "SAUK31 EGGY 181220 CCA\nMETAR EGLL 181220Z 24012KT CAVOK 15/08 Q1013=\nEGKK 181220Z COR 24010KT CAVOK 14/07 Q1013=",
+			wantCorrection: true,
+			want:           []string{"EGLL 181220Z COR 24012KT CAVOK 15/08 Q1013", "EGKK 181220Z COR 24010KT CAVOK 14/07 Q1013"},
+		},
+		{
+			name:        "delayed bulletin",
+			text:        "SAUK31 EGGY 181220 RRA\nMETAR EGLL 181220Z 24012KT CAVOK 15/08 Q1013=",
+			wantDelayed: true,
+			want:        []string{"EGLL 181220Z 24012KT CAVOK 15/08 Q1013"},
+		},
+		{
+			name: "corrected report",
+			text: "SAUK31 EGGY 181220\nMETAR COR EGLL 181220Z 24012KT CAVOK 15/08 Q1013=\nEGKK 181220Z 24010KT CAVOK 14/07 Q1013=",
+			want: []string{"EGLL 181220Z COR 24012KT CAVOK 15/08 Q1013", "EGKK 181220Z 24010KT CAVOK 14/07 Q1013"},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			bulletin, err := Parse(test.text)
+			if err != nil {
+				t.Fatalf("Parse() error = %v", err)
+			}
+			if got := bulletin.Heading.IsCorrection(); got != test.wantCorrection {
+				t.Errorf("IsCorrection() = %v, want %v", got, test.wantCorrection)
+			}
+			if got := bulletin.Heading.IsDelayed(); got != test.wantDelayed {
+				t.Errorf("IsDelayed() = %v, want %v", got, test.wantDelayed)
+			}
+			assertTexts(t, reportTexts(bulletin), test.want...)
+		})
+	}
+}
+
+func TestParseKeywords(t *testing.T) {
+	bulletin, err := Parse("SPUK31 EGGY 181235\nSPECI EGLL 181235Z 24020G35KT 3000 TSRA BKN010CB 14/10 Q1012=\n" +
+		"METAR EGKK 181220Z 24010KT CAVOK 14/07 Q1013=\nEGSS 181220Z 23008KT CAVOK 13/06 Q1014=")
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+	assertTexts(t, reportTexts(bulletin),
+		"SPECI EGLL 181235Z 24020G35KT 3000 TSRA BKN010CB 14/10 Q1012",
+		"EGKK 181220Z 24010KT CAVOK 14/07 Q1013",
+		"EGSS 181220Z 23008KT CAVOK 13/06 Q1014",
+	)
+
+	// SPECI bulletins apply their keyword to reports without their own
+	bulletin, err = Parse("SPUK31 EGGY 181235\nEGLL 181235Z 24020G35KT 3000 TSRA BKN010CB 14/10 Q1012=")
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+	if keyword := bulletin.Reports[0].Keyword; keyword != KeywordSPECI {
+		t.Errorf("keyword = %s, want %s", keyword, KeywordSPECI)
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	if _, err := Parse("METAR EGLL 181220Z 24012KT CAVOK 15/08 Q1013="); !errors.Is(err, ErrNoHeading) {
+		t.Errorf("Parse() without heading error = %v, want %v", err, ErrNoHeading)
+	}
+	if _, err := Parse("FTUK31 EGGY 181100\nTAF EGLL 181100Z 1812/1918 24012KT CAVOK="); err == nil {
+		t.Error("Parse() of a TAF bulletin did not fail")
+	}
+}
+
+func TestSplit(t *testing.T) {
+	stream := collective + "\x01\r\r\n124\r\r\nSPUK31 EGGY 181235\r\r\nSPECI EGLL 181235Z 24020G35KT 3000 TSRA BKN010CB 14/10 Q1012=\r\r\n\x03" +
+		"SAUK32 EGGY 181250\nMETAR EGSS 181250Z 23008KT CAVOK 13/06 Q1014=\n" +
+		"SAUK33 EGGY 181250\nMETAR EGGW 181250Z 23009KT CAVOK 13/06 Q1014=\nNNNN\n"
+	bulletins := Split(stream)
+	if len(bulletins) != 4 {
+		t.Fatalf("Split() returned %d bulletins, want 4: %q", len(bulletins), bulletins)
+	}
+	for i, designator := range []string{"SAUK31", "SPUK31", "SAUK32", "SAUK33"} {
+		if bulletin, err := Parse(bulletins[i]); err != nil || bulletin.Heading.Designator != designator {
+			t.Errorf("bulletin %d = %q, want the one with heading %s", i, bulletins[i], designator)
+		}
+	}
+
+	reports, err := Extract(bufio.NewReader(strings.NewReader(stream)))
+	if err != nil {
+		t.Fatalf("Extract() error = %v", err)
+	}
+	got := reports.ToSlice()
+	sort.Strings(got)
+	assertTexts(t, got,
+		"EGGW 181250Z 23009KT CAVOK 13/06 Q1014",
+		"EGKK 181220Z 24010KT 9999 SCT025 14/07 Q1013",
+		"EGLL 181220Z 24012KT 9999 FEW030 15/08 Q1013 NOSIG",
+		"EGSS 181250Z 23008KT CAVOK 13/06 Q1014",
+		"SPECI EGLL 181235Z 24020G35KT 3000 TSRA BKN010CB 14/10 Q1012",
+	)
+}
@@ -0,0 +1,66 @@
+package bulletin
+
+import (
+	"bufio"
+	"errors"
+	"github.com/rs/zerolog/log"
+	"github.com/skybi/nuntius/internal/set"
+	"github.com/skybi/nuntius/internal/source"
+	"io"
+	"strings"
+)
+
+// Split splits a stream of multiple bulletins into the texts of the single bulletins.
+// A bulletin ends with an NNNN or ETX end-of-message signal or as soon as the next abbreviated heading starts.
+func Split(text string) []string {
+	var bulletins []string
+	var current []string
+	hasHeading := false
+	flush := func() {
+		if hasHeading {
+			bulletins = append(bulletins, strings.Join(current, "\n"))
+		}
+		current = nil
+		hasHeading = false
+	}
+
+	for _, line := range splitLines(strings.ReplaceAll(text, "\x03", "\nNNNN\n")) {
+		switch {
+		case line == "NNNN":
+			flush()
+		case headingRegex.MatchString(line):
+			if hasHeading {
+				flush()
+			}
+			hasHeading = true
+			current = append(current, line)
+		case line != "":
+			current = append(current, line)
+		}
+	}
+	flush()
+	return bulletins
+}
+
+// Extract extracts and deduplicates the raw METARs and SPECIs contained in a stream of WMO bulletins.
+// SPECIs are prefixed with their keyword.
+// Bulletins that can not be parsed or contain other data types are skipped.
+func Extract(reader *bufio.Reader) (*set.HashSet[string], error) {
+	data, err := io.ReadAll(reader)
+	if err != nil && !errors.Is(err, io.EOF) {
+		return nil, err
+	}
+
+	hashSet := set.NewHashSet[string]()
+	for _, text := range Split(string(data)) {
+		bulletin, err := Parse(text)
+		if err != nil {
+			log.Debug().Err(err).Msg("skipping bulletin")
+			continue
+		}
+		for _, report := range bulletin.Reports {
+			hashSet.Add(source.WithKeyword(report.Keyword, report.Raw))
+		}
+	}
+	return hashSet, nil
+}
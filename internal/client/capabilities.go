@@ -2,4 +2,5 @@ package client
 
 const (
 	CapabilityFeedMETARs uint = 1 << 1
+	CapabilityFeedTAFs   uint = 1 << 2
 )
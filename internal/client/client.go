@@ -40,10 +40,20 @@ func (client *Client) execute(request *http.Request) (*http.Response, []byte, er
 	}
 	if response.StatusCode < 200 || response.StatusCode > 299 {
 		var errResponse *APIErrorResponse
-		if err := json.Unmarshal(body, &errResponse); err == nil {
+		if err := json.Unmarshal(body, &errResponse); err == nil && errResponse != nil {
+			if errResponse.Status == 0 {
+				errResponse.Status = response.StatusCode
+			}
 			return nil, nil, errResponse
 		}
-		return nil, nil, fmt.Errorf("HTTP status %d: %s", response.StatusCode, string(body))
+		return nil, nil, &APIErrorResponse{
+			Status: response.StatusCode,
+			Errors: []*APIError{
+				{
+					Message: fmt.Sprintf("HTTP status %d: %s", response.StatusCode, string(body)),
+				},
+			},
+		}
 	}
 	return response, body, nil
 }
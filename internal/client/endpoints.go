@@ -3,4 +3,5 @@ package client
 const (
 	endpointKeyInfo = "/v1/key_info"
 	endpointMETARs  = "/v1/metars"
+	endpointTAFs    = "/v1/tafs"
 )
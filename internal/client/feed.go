@@ -0,0 +1,60 @@
+package client
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"github.com/rs/zerolog/log"
+	"net/http"
+)
+
+// feed feeds raw reports into the given data server endpoint and returns the indices of the duplicate ones
+func (client *Client) feed(endpoint string, reports []string) ([]int, error) {
+	data, err := json.Marshal(map[string][]string{
+		"data": reports,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	request, err := http.NewRequest(http.MethodPost, client.address+endpoint, bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+
+	_, body, err := client.execute(request)
+	if err != nil {
+		return nil, err
+	}
+
+	responseData := new(struct {
+		Duplicates []int `json:"duplicates"`
+	})
+	if err := json.Unmarshal(body, responseData); err != nil {
+		return nil, err
+	}
+
+	return responseData.Duplicates, nil
+}
+
+// feedRelaxed calls feed and retries it without every report the data server rejects with the given error type
+func feedRelaxed(feed func(reports []string) ([]int, error), invalidFormatType string, reports []string) error {
+	_, err := feed(reports)
+	if err != nil {
+		var errResponse *APIErrorResponse
+		if !errors.As(err, &errResponse) || len(errResponse.Errors) == 0 {
+			return err
+		}
+		if errResponse.Errors[0].Type != invalidFormatType {
+			return err
+		}
+		invalid := int(errResponse.Errors[0].Details["index"].(float64))
+		log.Warn().Msgf("skipping invalid report: '%s'", reports[invalid])
+		// Build a new slice instead of removing the report in place as the caller may still use the batch
+		remaining := make([]string, 0, len(reports)-1)
+		remaining = append(remaining, reports[:invalid]...)
+		remaining = append(remaining, reports[invalid+1:]...)
+		return feedRelaxed(feed, invalidFormatType, remaining)
+	}
+	return nil
+}
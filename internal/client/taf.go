@@ -0,0 +1,11 @@
+package client
+
+// FeedTAFs feeds TAFs into the data server
+func (client *Client) FeedTAFs(tafs []string) ([]int, error) {
+	return client.feed(endpointTAFs, tafs)
+}
+
+// FeedTAFsRelaxed works the same as FeedTAFs with the exception that it simply skips TAFs with an invalid format
+func (client *Client) FeedTAFsRelaxed(tafs []string) error {
+	return feedRelaxed(client.FeedTAFs, "data.tafs.invalidFormat", tafs)
+}
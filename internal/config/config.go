@@ -4,6 +4,7 @@ import (
 	"github.com/joho/godotenv"
 	"github.com/kelseyhightower/envconfig"
 	"strings"
+	"time"
 )
 
 // Config represents the application configuration structure
@@ -13,7 +14,59 @@ type Config struct {
 	APIAddress string `default:"http://localhost:8082" split_words:"true"`
 	APIKey     string `split_words:"true"`
 
-	FeedMETARs bool `envconfig:"feed_metars"`
+	FeedMETARs    bool     `envconfig:"feed_metars"`
+	METARSinks    []string `default:"api" envconfig:"metar_sinks"`
+	METARSinkFile string   `default:"./data/metar/sink.txt" envconfig:"metar_sink_file"`
+
+	METARChangeDetection string `default:"auto" envconfig:"metar_change_detection"`
+
+	AWCEnabled  bool          `envconfig:"awc_enabled"`
+	AWCFormat   string        `default:"csv" envconfig:"awc_format"`
+	AWCURL      string        `envconfig:"awc_url"`
+	AWCInterval time.Duration `default:"1m" envconfig:"awc_interval"`
+
+	SpoolDir      string        `envconfig:"spool_dir"`
+	SpoolFormat   string        `default:"text" envconfig:"spool_format"`
+	SpoolInterval time.Duration `default:"5s" envconfig:"spool_interval"`
+	SpoolSettle   time.Duration `default:"1s" envconfig:"spool_settle"`
+
+	FeedTAFs    bool     `envconfig:"feed_tafs"`
+	TAFSinks    []string `default:"api" envconfig:"taf_sinks"`
+	TAFSinkFile string   `default:"./data/taf/sink.txt" envconfig:"taf_sink_file"`
+
+	TAFChangeDetection string `default:"auto" envconfig:"taf_change_detection"`
+
+	DedupExpiry time.Duration `default:"48h" envconfig:"dedup_expiry"`
+
+	QueueCapacity     int     `default:"100000" envconfig:"queue_capacity"`
+	QueueOverflow     string  `default:"spill" envconfig:"queue_overflow"`
+	QueueOrder        string  `default:"fifo" envconfig:"queue_order"`
+	QueueBacklogShare float64 `default:"0.2" envconfig:"queue_backlog_share"`
+	QueueMaxAttempts  int     `default:"5" envconfig:"queue_max_attempts"`
+
+	MaxReportAge time.Duration `default:"0" envconfig:"max_report_age"`
+
+	IngestAddress string `envconfig:"ingest_address"`
+	IngestKey     string `envconfig:"ingest_key"`
+
+	FTPAddress     string        `default:"tgftp.nws.noaa.gov:21" envconfig:"ftp_address"`
+	FTPUser        string        `default:"anonymous" envconfig:"ftp_user"`
+	FTPPassword    string        `default:"anonymous" envconfig:"ftp_password"`
+	FTPBasePath    string        `default:"/data/" envconfig:"ftp_base_path"`
+	FTPDialTimeout time.Duration `default:"5s" envconfig:"ftp_dial_timeout"`
+	FTPMaxConns    int           `default:"4" envconfig:"ftp_max_conns"`
+	FTPIdleTimeout time.Duration `default:"1m" envconfig:"ftp_idle_timeout"`
+	FTPBackoffMin  time.Duration `default:"1s" envconfig:"ftp_backoff_min"`
+	FTPBackoffMax  time.Duration `default:"5m" envconfig:"ftp_backoff_max"`
+
+	CycleTransport      string        `default:"ftp" envconfig:"cycle_transport"`
+	CycleFilePattern    string        `default:"%02dZ.TXT" envconfig:"cycle_file_pattern"`
+	CycleActiveInterval time.Duration `default:"30s" envconfig:"cycle_active_interval"`
+	CyclePeakInterval   time.Duration `default:"10s" envconfig:"cycle_peak_interval"`
+	CycleStaleInterval  time.Duration `default:"15m" envconfig:"cycle_stale_interval"`
+
+	HTTPBaseURL string        `default:"https://tgftp.nws.noaa.gov/data/" envconfig:"http_base_url"`
+	HTTPTimeout time.Duration `default:"30s" envconfig:"http_timeout"`
 }
 
 // LoadFromEnv loads a new configuration structure using environment variables and an optional .env file
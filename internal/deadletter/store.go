@@ -0,0 +1,145 @@
+package deadletter
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"github.com/skybi/nuntius/internal/file"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// Letter represents a report that could not be fed after the maximum amount of attempts
+type Letter struct {
+	Report    string    `json:"report"`
+	Attempts  int       `json:"attempts"`
+	LastError string    `json:"last_error"`
+	DeadAt    time.Time `json:"dead_at"`
+}
+
+// Store represents a persistent store of dead letters, kept as a file containing one JSON object per line so that it
+// can be inspected using common tools
+type Store struct {
+	sync.Mutex
+	filepath string
+}
+
+// New creates a new dead-letter store persisting its letters into filepath
+func New(filepath string) *Store {
+	return &Store{
+		filepath: filepath,
+	}
+}
+
+// Path returns the path of the file the letters are persisted into
+func (store *Store) Path() string {
+	return store.filepath
+}
+
+// Add appends letters to the store and flushes them to disk
+func (store *Store) Add(letters ...*Letter) error {
+	if len(letters) == 0 {
+		return nil
+	}
+
+	var buffer bytes.Buffer
+	encoder := json.NewEncoder(&buffer)
+	for _, letter := range letters {
+		if err := encoder.Encode(letter); err != nil {
+			return err
+		}
+	}
+
+	store.Lock()
+	defer store.Unlock()
+
+	abs, err := filepath.Abs(store.filepath)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(abs), 0750); err != nil && !errors.Is(err, os.ErrExist) {
+		return err
+	}
+
+	letterFile, err := os.OpenFile(abs, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0640)
+	if err != nil {
+		return err
+	}
+	defer letterFile.Close()
+
+	if _, err := letterFile.Write(buffer.Bytes()); err != nil {
+		return err
+	}
+	return letterFile.Sync()
+}
+
+// List returns every letter of the store in the order they were added.
+// A torn line at the end of the file stems from an interrupted write and is ignored.
+func (store *Store) List() ([]*Letter, error) {
+	store.Lock()
+	defer store.Unlock()
+	return store.list()
+}
+
+func (store *Store) list() ([]*Letter, error) {
+	data, err := file.Read(store.filepath)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	var letters []*Letter
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	scanner.Buffer(nil, 1<<20)
+	for scanner.Scan() {
+		line := bytes.TrimSpace(scanner.Bytes())
+		if len(line) == 0 {
+			continue
+		}
+		letter := new(Letter)
+		if err := json.Unmarshal(line, letter); err != nil {
+			if !bytes.HasSuffix(data, []byte("\n")) && bytes.HasSuffix(bytes.TrimSpace(data), line) {
+				break
+			}
+			return nil, err
+		}
+		letters = append(letters, letter)
+	}
+	return letters, scanner.Err()
+}
+
+// Replay passes every letter to fn, which returns the amount of leading letters it took over. Those are removed from
+// the store while the others are kept, even if fn fails.
+func (store *Store) Replay(fn func(letters []*Letter) (int, error)) (int, error) {
+	store.Lock()
+	defer store.Unlock()
+
+	letters, err := store.list()
+	if err != nil || len(letters) == 0 {
+		return 0, err
+	}
+	replayed, fnErr := fn(letters)
+	if replayed <= 0 {
+		return 0, fnErr
+	}
+	if replayed > len(letters) {
+		replayed = len(letters)
+	}
+
+	var buffer bytes.Buffer
+	encoder := json.NewEncoder(&buffer)
+	for _, letter := range letters[replayed:] {
+		if err := encoder.Encode(letter); err != nil {
+			return 0, err
+		}
+	}
+	if err := file.Write(store.filepath, buffer.Bytes()); err != nil {
+		return 0, err
+	}
+	return replayed, fnErr
+}
@@ -0,0 +1,138 @@
+package dedup
+
+import (
+	"errors"
+	"github.com/fxamacker/cbor/v2"
+	"github.com/skybi/nuntius/internal/file"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// expiryInterval is the minimum interval between two sweeps removing expired identities from memory
+const expiryInterval = time.Minute
+
+var decMode cbor.DecMode
+
+func init() {
+	mode, err := cbor.DecOptions{
+		MaxMapPairs: 2000000,
+	}.DecMode()
+	if err != nil {
+		panic(err)
+	}
+	decMode = mode
+}
+
+// Index represents a persistent index of the identities of all reports queued within the expiry window, used to
+// deduplicate reports across cycle files and sources
+type Index struct {
+	sync.Mutex
+	filepath string
+	expiry   time.Duration
+
+	// seen maps the identity of a report to the unix time it was first seen at
+	seen       map[string]int64
+	lastExpiry time.Time
+}
+
+// New creates a new deduplication index forgetting reports after expiry and persisting itself into filepath
+func New(filepath string, expiry time.Duration) *Index {
+	return &Index{
+		filepath: filepath,
+		expiry:   expiry,
+		seen:     make(map[string]int64),
+	}
+}
+
+// Identity returns the normalized identity of a raw report so that reports only differing in their formatting are
+// treated as equal
+func Identity(raw string) string {
+	identity := strings.Join(strings.Fields(strings.ToUpper(raw)), " ")
+	identity = strings.TrimSpace(strings.TrimSuffix(identity, "="))
+	for _, keyword := range []string{"METAR ", "SPECI ", "TAF "} {
+		identity = strings.TrimPrefix(identity, keyword)
+	}
+	return identity
+}
+
+// Filter returns the reports that were not seen within the expiry window and records them as seen.
+// Duplicates inside reports are only returned once.
+func (index *Index) Filter(reports []string) []string {
+	index.Lock()
+	defer index.Unlock()
+
+	now := time.Now()
+	if now.Sub(index.lastExpiry) >= expiryInterval {
+		index.expire(now)
+	}
+
+	fresh := make([]string, 0, len(reports))
+	for _, report := range reports {
+		identity := Identity(report)
+		if seen, ok := index.seen[identity]; ok && !index.expired(seen, now) {
+			continue
+		}
+		index.seen[identity] = now.Unix()
+		fresh = append(fresh, report)
+	}
+	return fresh
+}
+
+// Forget removes the identities of the given reports so that they are not treated as duplicates anymore
+func (index *Index) Forget(reports []string) {
+	index.Lock()
+	defer index.Unlock()
+	for _, report := range reports {
+		delete(index.seen, Identity(report))
+	}
+}
+
+// Load restores the index from its file if it exists
+func (index *Index) Load() error {
+	data, err := file.Read(index.filepath)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil
+		}
+		return err
+	}
+
+	seen := make(map[string]int64)
+	if err := decMode.Unmarshal(data, &seen); err != nil {
+		return err
+	}
+
+	index.Lock()
+	defer index.Unlock()
+	index.seen = seen
+	index.expire(time.Now())
+	return nil
+}
+
+// Save persists the index into its file, dropping expired identities beforehand
+func (index *Index) Save() error {
+	index.Lock()
+	defer index.Unlock()
+	index.expire(time.Now())
+
+	data, err := cbor.Marshal(index.seen)
+	if err != nil {
+		return err
+	}
+	return file.WriteWithChecksum(index.filepath, data)
+}
+
+func (index *Index) expire(now time.Time) {
+	index.lastExpiry = now
+	for identity, seen := range index.seen {
+		if index.expired(seen, now) {
+			delete(index.seen, identity)
+		}
+	}
+}
+
+func (index *Index) expired(seen int64, now time.Time) bool {
+	return now.Sub(time.Unix(seen, 0)) >= index.expiry
+}
@@ -0,0 +1,470 @@
+package feeder
+
+import (
+	"errors"
+	"fmt"
+	"github.com/rs/zerolog/log"
+	"github.com/skybi/nuntius/internal/deadletter"
+	"github.com/skybi/nuntius/internal/dedup"
+	"github.com/skybi/nuntius/internal/file"
+	"github.com/skybi/nuntius/internal/queue"
+	"github.com/skybi/nuntius/internal/sink"
+	"github.com/skybi/nuntius/internal/source"
+	"github.com/skybi/nuntius/internal/timestamp"
+	"os"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	queueDirName        = "feeder-queue"
+	dedupIndexFileName  = "dedup-index"
+	deadLettersFileName = "dead-letters.ndjson"
+)
+
+// indexSaveInterval is the interval in which the deduplication index is persisted while the feeder is running so that
+// a crash does not lose the identities of the reports queued since the last start
+const indexSaveInterval = time.Minute
+
+// ErrNotRunning is returned when reports are queued into a feeder that is not running
+var ErrNotRunning = errors.New("feeder is not running")
+
+// FixFunc applies fixes on a raw report before it gets queued
+type FixFunc func(raw string) string
+
+// Options represents the tunables of a feeder
+type Options struct {
+	BatchSize int
+	Interval  time.Duration
+
+	// DedupExpiry is how long queued reports are remembered to discard duplicates; 0 disables deduplication
+	DedupExpiry time.Duration
+
+	// MaxAttempts is the amount of times a report may be rejected by the sink before it is moved into the dead-letter
+	// store; 0 retries rejected reports forever
+	MaxAttempts int
+
+	// MaxAge is the age reports are discarded at, determined by the time they were observed or issued at; 0 keeps
+	// reports regardless of their age
+	MaxAge time.Duration
+
+	Queue queue.Options
+}
+
+// Feeder represents the worker queueing and feeding new reports of a single kind emitted by the sources
+type Feeder struct {
+	sync.RWMutex
+
+	// expired is the amount of reports discarded because they exceeded the maximum age; it is accessed atomically
+	expired uint64
+
+	name  string
+	queue *queue.WAL[string]
+	fix   FixFunc
+	index *dedup.Index
+
+	deadLetters *deadletter.Store
+
+	sink    sink.Sink
+	options Options
+
+	// queuePath is the directory of the write-ahead log backing the queue; versions before it backed up the queue into
+	// a single file at the same location
+	queuePath string
+
+	running bool
+	stop    chan struct{}
+	done    chan struct{}
+}
+
+// New creates a new feeder draining its queue into the given sink and persisting its queue, deduplication index and
+// dead letters into dataDir
+func New(name string, sink sink.Sink, fix FixFunc, dataDir string, options Options) *Feeder {
+	var index *dedup.Index
+	if options.DedupExpiry > 0 {
+		index = dedup.New(filepath.Join(dataDir, dedupIndexFileName), options.DedupExpiry)
+	}
+	return &Feeder{
+		name:        name,
+		fix:         fix,
+		index:       index,
+		deadLetters: deadletter.New(filepath.Join(dataDir, deadLettersFileName)),
+		sink:        sink,
+		options:     options,
+		queuePath:   filepath.Join(dataDir, queueDirName),
+	}
+}
+
+// Queue queues reports to feed, fixing them and discarding the ones that were already queued beforehand.
+// The reports are persisted before Queue returns; if it returns an error, none of them were queued.
+// Reports dropped by the drop-newest policy may be queued again, e.g. by another source, while the ones evicted by the
+// drop-oldest policy count as queued and are discarded as duplicates until they expire.
+func (feeder *Feeder) Queue(reports []string) error {
+	// Do not hold the lock while pushing as it may block until the feeder is stopped
+	feeder.RLock()
+	wal := feeder.queue
+	feeder.RUnlock()
+	if wal == nil {
+		return ErrNotRunning
+	}
+
+	if feeder.fix != nil {
+		for i, report := range reports {
+			reports[i] = feeder.fix(report)
+		}
+	}
+	reports = feeder.discardExpired(reports)
+	if feeder.index != nil {
+		fresh := feeder.index.Filter(reports)
+		if discarded := len(reports) - len(fresh); discarded > 0 {
+			log.Debug().Str("feeder", feeder.name).Int("amount", discarded).Msg("discarded duplicate reports")
+		}
+		reports = fresh
+	}
+
+	dropped, err := wal.Push(reports...)
+	if err != nil {
+		// Forget the reports so that they are not discarded as duplicates when they are emitted again
+		if feeder.index != nil {
+			feeder.index.Forget(reports)
+		}
+		return err
+	}
+	if dropped > 0 {
+		// Reports dropped from the tail were never queued and must not be discarded as duplicates later on
+		if feeder.index != nil && feeder.options.Queue.Overflow == queue.OverflowDropNewest {
+			feeder.index.Forget(reports[len(reports)-dropped:])
+		}
+		log.Warn().Str("feeder", feeder.name).Str("policy", feeder.options.Queue.Overflow.String()).Int("amount", dropped).Uint64("total", wal.Dropped()).Msg("queue is full; dropped reports")
+	}
+	return nil
+}
+
+// Receive queues the reports emitted by a source; it may be used as a source.EmitFunc
+func (feeder *Feeder) Receive(reports []*source.Report) error {
+	if len(reports) == 0 {
+		return nil
+	}
+	return feeder.Queue(source.Raws(reports))
+}
+
+// Start opens the queue and starts the feeding task
+func (feeder *Feeder) Start() error {
+	feeder.Lock()
+	defer feeder.Unlock()
+	if feeder.running {
+		return nil
+	}
+
+	wal, err := feeder.openQueue(feeder.options.Queue)
+	if err != nil {
+		return err
+	}
+	if feeder.index != nil {
+		if err := feeder.index.Load(); err != nil {
+			wal.Close()
+			return err
+		}
+	}
+	feeder.queue = wal
+
+	feeder.running = true
+	feeder.stop = make(chan struct{})
+	feeder.done = make(chan struct{})
+	go func() {
+		defer close(feeder.done)
+		feedTimer := time.NewTimer(feeder.options.Interval)
+		defer feedTimer.Stop()
+		saveTicker := time.NewTicker(indexSaveInterval)
+		defer saveTicker.Stop()
+		for {
+			select {
+			case <-feeder.stop:
+				return
+			case <-saveTicker.C:
+				feeder.saveIndex()
+			case <-feedTimer.C:
+				feeder.feed()
+				feedTimer.Reset(feeder.options.Interval)
+			}
+		}
+	}()
+	return nil
+}
+
+// saveIndex persists the deduplication index, logging failures as the next attempt or Stop saves it again
+func (feeder *Feeder) saveIndex() {
+	if feeder.index == nil {
+		return
+	}
+	if err := feeder.index.Save(); err != nil {
+		log.Err(err).Str("feeder", feeder.name).Msg("could not save the deduplication index")
+	}
+}
+
+// feed feeds a single batch of queued reports into the sink
+func (feeder *Feeder) feed() {
+	if feeder.queue.Size() == 0 {
+		return
+	}
+
+	entries := feeder.queue.PopN(feeder.options.BatchSize)
+	if feeder.options.MaxAge > 0 {
+		now := time.Now()
+		var current, expired []*queue.Entry[string]
+		for _, entry := range entries {
+			if feeder.isExpired(entry.Value, now) {
+				expired = append(expired, entry)
+			} else {
+				current = append(current, entry)
+			}
+		}
+		if len(expired) > 0 {
+			if err := feeder.queue.Ack(expired...); err != nil {
+				feeder.queue.Requeue(expired...)
+				log.Err(err).Str("feeder", feeder.name).Msg("could not acknowledge expired reports")
+			} else {
+				feeder.countExpired(len(expired))
+			}
+		}
+		entries = current
+	}
+	if len(entries) > 0 {
+		feeder.feedEntries(entries)
+	}
+}
+
+// feedEntries feeds queued reports into the sink and acknowledges them if they were accepted.
+// If the sink rejected them, the batch is split up to isolate the rejected reports so that the others are not held
+// back by them.
+func (feeder *Feeder) feedEntries(entries []*queue.Entry[string]) {
+	values := make([]string, 0, len(entries))
+	for _, entry := range entries {
+		values = append(values, entry.Value)
+	}
+
+	err := feeder.sink.Feed(values)
+	if err == nil {
+		if err := feeder.queue.Ack(entries...); err != nil {
+			log.Err(err).Str("feeder", feeder.name).Msg("could not acknowledge fed reports")
+			return
+		}
+		log.Debug().Str("feeder", feeder.name).Str("sink", feeder.sink.Name()).Int("amount", len(values)).Msg("fed reports")
+		return
+	}
+
+	if !errors.Is(err, sink.ErrRejected) {
+		feeder.queue.Requeue(entries...)
+		log.Err(err).Str("feeder", feeder.name).Str("sink", feeder.sink.Name()).Msg("could not feed reports; appending them to the queue again")
+		return
+	}
+	if len(entries) > 1 {
+		half := len(entries) / 2
+		feeder.feedEntries(entries[:half])
+		feeder.feedEntries(entries[half:])
+		return
+	}
+	feeder.reject(entries[0], err)
+}
+
+// reject records a rejection of a single report and moves it into the dead-letter store once it reached the maximum
+// amount of attempts
+func (feeder *Feeder) reject(entry *queue.Entry[string], reason error) {
+	if err := feeder.queue.Fail(reason.Error(), entry); err != nil {
+		log.Err(err).Str("feeder", feeder.name).Msg("could not record failed attempt")
+	}
+	if feeder.options.MaxAttempts <= 0 || entry.Attempts < feeder.options.MaxAttempts {
+		feeder.queue.Requeue(entry)
+		log.Warn().Err(reason).Str("feeder", feeder.name).Str("sink", feeder.sink.Name()).Str("report", entry.Value).Int("attempts", entry.Attempts).Msg("report was rejected; appending it to the queue again")
+		return
+	}
+
+	err := feeder.deadLetters.Add(&deadletter.Letter{
+		Report:    entry.Value,
+		Attempts:  entry.Attempts,
+		LastError: entry.LastError,
+		DeadAt:    time.Now(),
+	})
+	if err != nil {
+		feeder.queue.Requeue(entry)
+		log.Err(err).Str("feeder", feeder.name).Msg("could not store dead letter; appending the report to the queue again")
+		return
+	}
+	if err := feeder.queue.Ack(entry); err != nil {
+		log.Err(err).Str("feeder", feeder.name).Msg("could not acknowledge dead letter")
+		return
+	}
+	log.Warn().Err(reason).Str("feeder", feeder.name).Str("sink", feeder.sink.Name()).Str("report", entry.Value).Int("attempts", entry.Attempts).Msg("report was rejected too often; moved it into the dead-letter store")
+}
+
+// discardExpired returns the reports that did not exceed the maximum age yet
+func (feeder *Feeder) discardExpired(reports []string) []string {
+	if feeder.options.MaxAge <= 0 {
+		return reports
+	}
+	now := time.Now()
+	current := make([]string, 0, len(reports))
+	for _, report := range reports {
+		if !feeder.isExpired(report, now) {
+			current = append(current, report)
+		}
+	}
+	feeder.countExpired(len(reports) - len(current))
+	return current
+}
+
+// isExpired reports whether a report exceeded the maximum age; reports without a time group never expire
+func (feeder *Feeder) isExpired(raw string, now time.Time) bool {
+	t, ok := timestamp.Parse(raw, now)
+	return ok && now.Sub(t) > feeder.options.MaxAge
+}
+
+// countExpired counts discarded expired reports and logs them
+func (feeder *Feeder) countExpired(amount int) {
+	if amount <= 0 {
+		return
+	}
+	total := atomic.AddUint64(&feeder.expired, uint64(amount))
+	log.Info().Str("feeder", feeder.name).Dur("max_age", feeder.options.MaxAge).Int("amount", amount).Uint64("total", total).Msg("discarded expired reports")
+}
+
+// DeadLetters returns the reports that were moved into the dead-letter store
+func (feeder *Feeder) DeadLetters() ([]*deadletter.Letter, error) {
+	return feeder.deadLetters.List()
+}
+
+// ReplayDeadLetters moves every dead letter back into the queue with its attempts reset and returns their amount.
+// The queue is opened temporarily and without a capacity if the feeder is not running. Letters that could not be
+// queued because it is full stay in the store.
+func (feeder *Feeder) ReplayDeadLetters() (int, error) {
+	feeder.Lock()
+	defer feeder.Unlock()
+
+	wal := feeder.queue
+	if wal == nil {
+		var err error
+		wal, err = feeder.openQueue(queue.Options{
+			SegmentSize: feeder.options.Queue.SegmentSize,
+		})
+		if err != nil {
+			return 0, err
+		}
+		defer wal.Close()
+	}
+
+	return feeder.deadLetters.Replay(func(letters []*deadletter.Letter) (int, error) {
+		reports := make([]string, 0, len(letters))
+		for _, letter := range letters {
+			reports = append(reports, letter.Report)
+		}
+		dropped, err := wal.Push(reports...)
+		if err != nil {
+			return 0, err
+		}
+		if dropped == 0 {
+			return len(reports), nil
+		}
+		// Dropping the newest entries only drops the tail of the replayed letters, while dropping the oldest ones may
+		// have evicted any of them, so all of them are kept
+		replayed := 0
+		if feeder.options.Queue.Overflow == queue.OverflowDropNewest {
+			replayed = len(reports) - dropped
+		}
+		return replayed, fmt.Errorf("queue is full; kept %d dead letters", len(reports)-replayed)
+	})
+}
+
+// Stop stops the feeding task and closes the queue
+func (feeder *Feeder) Stop() error {
+	feeder.Lock()
+	defer feeder.Unlock()
+	if !feeder.running {
+		return nil
+	}
+	close(feeder.stop)
+	<-feeder.done
+	feeder.running = false
+
+	if feeder.index != nil {
+		if err := feeder.index.Save(); err != nil {
+			return err
+		}
+	}
+	err := feeder.queue.Close()
+	feeder.queue = nil
+	return err
+}
+
+// Inspect opens the queue of a stopped feeder and passes it to fn so that maintenance tools can inspect or modify it.
+// The queue is opened without a capacity and in FIFO order so that every queued report can be popped.
+func (feeder *Feeder) Inspect(fn func(wal *queue.WAL[string]) error) error {
+	feeder.Lock()
+	defer feeder.Unlock()
+	if feeder.running {
+		return errors.New("feeder is running")
+	}
+
+	wal, err := feeder.openQueue(queue.Options{
+		SegmentSize: feeder.options.Queue.SegmentSize,
+	})
+	if err != nil {
+		return err
+	}
+	if err := fn(wal); err != nil {
+		wal.Close()
+		return err
+	}
+	return wal.Close()
+}
+
+// openQueue opens the write-ahead log of the queue, migrating a queue backup file written by previous versions
+func (feeder *Feeder) openQueue(options queue.Options) (*queue.WAL[string], error) {
+	legacyPath := feeder.queuePath + ".legacy"
+	if stat, err := os.Stat(feeder.queuePath); err == nil && stat.Mode().IsRegular() {
+		if err := os.Rename(feeder.queuePath, legacyPath); err != nil {
+			return nil, err
+		}
+	}
+
+	wal, err := queue.Open[string](feeder.queuePath, options, priority)
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := file.Read(legacyPath)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		wal.Close()
+		return nil, err
+	}
+	if err == nil {
+		legacy, err := queue.Deserialize[string](data)
+		if err != nil {
+			wal.Close()
+			return nil, err
+		}
+		values := legacy.PopN(legacy.Size())
+		if _, err := wal.Push(values...); err != nil {
+			wal.Close()
+			return nil, err
+		}
+		if err := os.Remove(legacyPath); err != nil {
+			wal.Close()
+			return nil, err
+		}
+		log.Info().Str("feeder", feeder.name).Int("amount", len(values)).Msg("migrated queue backup into write-ahead log")
+	}
+
+	return wal, nil
+}
+
+// priority prioritizes reports by the time they were observed or issued at so that the newest ones are fed first when
+// the queue is ordered by priority; reports without a time group are fed last
+func priority(raw string) int64 {
+	t, ok := timestamp.Parse(raw, time.Now())
+	if !ok {
+		return 0
+	}
+	return t.Unix()
+}
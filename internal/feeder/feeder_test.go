@@ -0,0 +1,225 @@
+package feeder
+
+import (
+	"errors"
+	"fmt"
+	"github.com/skybi/nuntius/internal/deadletter"
+	"github.com/skybi/nuntius/internal/queue"
+	"github.com/skybi/nuntius/internal/sink"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+)
+
+// fakeSink accepts every batch not containing a poison report and rejects the others unless it is unavailable
+type fakeSink struct {
+	fed         []string
+	calls       int
+	unavailable bool
+}
+
+func (fake *fakeSink) Name() string {
+	return "fake"
+}
+
+func (fake *fakeSink) Feed(reports []string) error {
+	fake.calls++
+	if fake.unavailable {
+		return errors.New("connection refused")
+	}
+	for _, report := range reports {
+		if strings.Contains(report, "POISON") {
+			return fmt.Errorf("%w: invalid report", sink.ErrRejected)
+		}
+	}
+	fake.fed = append(fake.fed, reports...)
+	return nil
+}
+
+func newTestFeeder(t *testing.T, dataDir string, fake *fakeSink, options Options) *Feeder {
+	t.Helper()
+	feeder := New("test", fake, nil, dataDir, options)
+	wal, err := feeder.openQueue(options.Queue)
+	if err != nil {
+		t.Fatalf("openQueue() error = %v", err)
+	}
+	feeder.queue = wal
+	t.Cleanup(func() {
+		feeder.queue.Close()
+	})
+	return feeder
+}
+
+func reopen(t *testing.T, feeder *Feeder) {
+	t.Helper()
+	feeder.queue.Close()
+	wal, err := feeder.openQueue(feeder.options.Queue)
+	if err != nil {
+		t.Fatalf("openQueue() error = %v", err)
+	}
+	feeder.queue = wal
+}
+
+func TestFeederDeadLetter(t *testing.T) {
+	dataDir := t.TempDir()
+	fake := new(fakeSink)
+	feeder := newTestFeeder(t, dataDir, fake, Options{BatchSize: 8, MaxAttempts: 3})
+
+	reports := []string{"EDDF 1", "EDDM 2", "EDDH 3", "EDDL 4", "POISON 5", "EDDK 6", "EDDS 7", "EDDB 8"}
+	if err := feeder.Queue(append([]string(nil), reports...)); err != nil {
+		t.Fatalf("Queue() error = %v", err)
+	}
+
+	feeder.feed()
+	fed := append([]string(nil), fake.fed...)
+	sort.Strings(fed)
+	if want := []string{"EDDB 8", "EDDF 1", "EDDH 3", "EDDK 6", "EDDL 4", "EDDM 2", "EDDS 7"}; strings.Join(fed, "|") != strings.Join(want, "|") {
+		t.Errorf("fed %v, want %v", fed, want)
+	}
+	if size, inFlight := feeder.queue.Size(), feeder.queue.InFlight(); size != 1 || inFlight != 0 {
+		t.Fatalf("queue holds %d queued and %d popped reports, want 1 and 0", size, inFlight)
+	}
+
+	// The attempts have to survive restarts
+	reopen(t, feeder)
+	entries := feeder.queue.PopN(1)
+	if entries[0].Value != "POISON 5" || entries[0].Attempts != 1 || entries[0].LastError == "" {
+		t.Errorf("restored %q with %d attempts and last error %q, want %q with 1 attempt", entries[0].Value, entries[0].Attempts, entries[0].LastError, "POISON 5")
+	}
+	feeder.queue.Requeue(entries...)
+
+	feeder.feed()
+	if letters, err := feeder.DeadLetters(); err != nil || len(letters) != 0 {
+		t.Fatalf("DeadLetters() = %v, %v before reaching the maximum attempts", letters, err)
+	}
+	feeder.feed()
+	if size, inFlight := feeder.queue.Size(), feeder.queue.InFlight(); size != 0 || inFlight != 0 {
+		t.Errorf("queue holds %d queued and %d popped reports, want none", size, inFlight)
+	}
+
+	if _, err := os.Stat(filepath.Join(dataDir, deadLettersFileName)); err != nil {
+		t.Fatalf("dead-letter store was not written: %v", err)
+	}
+	letters, err := feeder.DeadLetters()
+	if err != nil {
+		t.Fatalf("DeadLetters() error = %v", err)
+	}
+	if len(letters) != 1 || letters[0].Report != "POISON 5" || letters[0].Attempts != 3 || !strings.Contains(letters[0].LastError, "invalid report") {
+		t.Fatalf("DeadLetters() = %+v, want the poison report after 3 attempts", letters)
+	}
+
+	// Replaying moves the report back into the queue with its attempts reset
+	if amount, err := feeder.ReplayDeadLetters(); err != nil || amount != 1 {
+		t.Fatalf("ReplayDeadLetters() = %d, %v, want 1", amount, err)
+	}
+	entries = feeder.queue.PopN(2)
+	if len(entries) != 1 || entries[0].Value != "POISON 5" || entries[0].Attempts != 0 {
+		t.Errorf("replayed entries %+v, want the poison report without attempts", entries)
+	}
+	if letters, _ := feeder.DeadLetters(); len(letters) != 0 {
+		t.Errorf("DeadLetters() = %+v after replaying, want none", letters)
+	}
+}
+
+func TestFeederUnavailableSink(t *testing.T) {
+	fake := &fakeSink{unavailable: true}
+	feeder := newTestFeeder(t, t.TempDir(), fake, Options{BatchSize: 8, MaxAttempts: 1})
+	if err := feeder.Queue([]string{"EDDF 1", "POISON 2", "EDDM 3"}); err != nil {
+		t.Fatalf("Queue() error = %v", err)
+	}
+
+	for i := 0; i < 3; i++ {
+		feeder.feed()
+	}
+	if fake.calls != 3 {
+		t.Errorf("sink was called %d times, want 3 without splitting the batch", fake.calls)
+	}
+	entries := feeder.queue.PopN(8)
+	if len(entries) != 3 {
+		t.Fatalf("queue holds %d reports, want 3", len(entries))
+	}
+	for _, entry := range entries {
+		if entry.Attempts != 0 {
+			t.Errorf("%q has %d attempts, want 0", entry.Value, entry.Attempts)
+		}
+	}
+	if letters, _ := feeder.DeadLetters(); len(letters) != 0 {
+		t.Errorf("DeadLetters() = %+v, want none", letters)
+	}
+}
+
+func TestFeederReplayDeadLettersFullQueue(t *testing.T) {
+	feeder := newTestFeeder(t, t.TempDir(), new(fakeSink), Options{
+		BatchSize:   8,
+		MaxAttempts: 3,
+		Queue:       queue.Options{Capacity: 2, Overflow: queue.OverflowDropNewest},
+	})
+	if err := feeder.Queue([]string{"EDDF 1"}); err != nil {
+		t.Fatalf("Queue() error = %v", err)
+	}
+	err := feeder.deadLetters.Add(
+		&deadletter.Letter{Report: "POISON 2", Attempts: 3},
+		&deadletter.Letter{Report: "POISON 3", Attempts: 3},
+		&deadletter.Letter{Report: "POISON 4", Attempts: 3},
+	)
+	if err != nil {
+		t.Fatalf("Add() error = %v", err)
+	}
+
+	// Letters not fitting into the queue of a running feeder stay in the store
+	if amount, err := feeder.ReplayDeadLetters(); err == nil || amount != 1 {
+		t.Fatalf("ReplayDeadLetters() = %d, %v, want 1 and an error", amount, err)
+	}
+	letters, err := feeder.DeadLetters()
+	if err != nil || len(letters) != 2 || letters[0].Report != "POISON 3" || letters[1].Report != "POISON 4" {
+		t.Fatalf("DeadLetters() = %+v, %v, want the letters that were not replayed", letters, err)
+	}
+
+	// The queue of a stopped feeder is opened without a capacity
+	feeder.queue.Close()
+	feeder.queue = nil
+	if amount, err := feeder.ReplayDeadLetters(); err != nil || amount != 2 {
+		t.Fatalf("ReplayDeadLetters() = %d, %v, want 2", amount, err)
+	}
+	if letters, _ := feeder.DeadLetters(); len(letters) != 0 {
+		t.Errorf("DeadLetters() = %+v after replaying, want none", letters)
+	}
+	err = feeder.Inspect(func(wal *queue.WAL[string]) error {
+		if size := wal.Size(); size != 4 {
+			t.Errorf("queue holds %d reports, want 4", size)
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("Inspect() error = %v", err)
+	}
+	wal, err := feeder.openQueue(queue.Options{})
+	if err != nil {
+		t.Fatalf("openQueue() error = %v", err)
+	}
+	feeder.queue = wal
+}
+
+func TestFeederDropNewestForgetsDropped(t *testing.T) {
+	feeder := newTestFeeder(t, t.TempDir(), new(fakeSink), Options{
+		BatchSize:   8,
+		DedupExpiry: time.Hour,
+		Queue:       queue.Options{Capacity: 2, Overflow: queue.OverflowDropNewest},
+	})
+	if err := feeder.Queue([]string{"EDDF 1", "EDDM 2", "EDDH 3"}); err != nil {
+		t.Fatalf("Queue() error = %v", err)
+	}
+	feeder.feed()
+
+	// The dropped report is queued once another source emits it again while the queued ones stay duplicates
+	if err := feeder.Queue([]string{"EDDF 1", "EDDH 3"}); err != nil {
+		t.Fatalf("Queue() error = %v", err)
+	}
+	entries := feeder.queue.PopN(8)
+	if len(entries) != 1 || entries[0].Value != "EDDH 3" {
+		t.Errorf("queue holds %+v, want only the previously dropped report", entries)
+	}
+}
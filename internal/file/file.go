@@ -1,34 +1,78 @@
 package file
 
 import (
+	"bytes"
+	"encoding/binary"
 	"errors"
+	"hash/crc32"
 	"io"
 	"os"
 	"path/filepath"
 )
 
-// Write writes bytes to a file, creating the file if it does not exist and overriding it if it does
+// checksumMagic marks the end of a checksum footer consisting of the CRC-32 checksum of the contents followed by it
+var checksumMagic = []byte("SBFCRC01")
+
+const footerSize = 4 + 8
+
+// ErrChecksumMismatch is returned when reading a file whose checksum footer does not match its contents
+var ErrChecksumMismatch = errors.New("file checksum mismatch")
+
+// Write atomically writes bytes to a file, creating the file if it does not exist and replacing it if it does.
+// The contents are written into a temporary file which is then renamed, so that a crash leaves either the old or the
+// new version of the file behind.
 func Write(path string, contents []byte) error {
 	abs, err := filepath.Abs(path)
 	if err != nil {
 		return err
 	}
+	dir := filepath.Dir(abs)
 
-	if err := os.MkdirAll(filepath.Dir(abs), 0750); err != nil && !errors.Is(err, os.ErrExist) {
+	if err := os.MkdirAll(dir, 0750); err != nil && !errors.Is(err, os.ErrExist) {
 		return err
 	}
 
-	file, err := os.Create(abs)
+	file, err := os.CreateTemp(dir, "."+filepath.Base(abs)+".*.tmp")
 	if err != nil {
 		return err
 	}
-	defer file.Close()
+	tmp := file.Name()
+	defer os.Remove(tmp)
+
+	if _, err := file.Write(contents); err != nil {
+		file.Close()
+		return err
+	}
+	if err := file.Chmod(0640); err != nil {
+		file.Close()
+		return err
+	}
+	if err := file.Sync(); err != nil {
+		file.Close()
+		return err
+	}
+	if err := file.Close(); err != nil {
+		return err
+	}
 
-	_, err = file.Write(contents)
-	return err
+	if err := os.Rename(tmp, abs); err != nil {
+		return err
+	}
+	return SyncDir(dir)
 }
 
-// Read reads bytes from a file
+// WriteWithChecksum atomically writes bytes to a file like Write and appends a checksum footer verified by Read
+func WriteWithChecksum(path string, contents []byte) error {
+	footer := make([]byte, 4, footerSize)
+	binary.BigEndian.PutUint32(footer, crc32.ChecksumIEEE(contents))
+	footer = append(footer, checksumMagic...)
+
+	data := make([]byte, 0, len(contents)+footerSize)
+	data = append(data, contents...)
+	return Write(path, append(data, footer...))
+}
+
+// Read reads bytes from a file. If the file ends with a checksum footer, it is verified and stripped.
 func Read(path string) ([]byte, error) {
 	abs, err := filepath.Abs(path)
 	if err != nil {
@@ -53,5 +97,23 @@ func Read(path string) ([]byte, error) {
 	if err != nil {
 		return nil, err
 	}
+
+	if len(data) >= footerSize && bytes.Equal(data[len(data)-len(checksumMagic):], checksumMagic) {
+		contents := data[:len(data)-footerSize]
+		if crc32.ChecksumIEEE(contents) != binary.BigEndian.Uint32(data[len(contents):]) {
+			return nil, ErrChecksumMismatch
+		}
+		return contents, nil
+	}
 	return data, nil
 }
+
+// SyncDir flushes a directory so that files created, renamed or removed inside of it survive a crash
+func SyncDir(dir string) error {
+	file, err := os.Open(dir)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	return file.Sync()
+}
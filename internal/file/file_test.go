@@ -0,0 +1,133 @@
+package file
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestWriteWithChecksum(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "state")
+	contents := []byte("EDDF 181220Z 24012KT CAVOK 15/08 Q1013")
+	if err := WriteWithChecksum(path, contents); err != nil {
+		t.Fatalf("WriteWithChecksum() error = %v", err)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(data) != len(contents)+footerSize || !bytes.HasSuffix(data, checksumMagic) {
+		t.Errorf("file = %q, want the contents followed by a checksum footer", data)
+	}
+	if got, err := Read(path); err != nil || !bytes.Equal(got, contents) {
+		t.Errorf("Read() = %q, %v, want %q", got, err, contents)
+	}
+
+	// Empty contents still get a footer
+	if err := WriteWithChecksum(path, nil); err != nil {
+		t.Fatalf("WriteWithChecksum() error = %v", err)
+	}
+	if got, err := Read(path); err != nil || len(got) != 0 {
+		t.Errorf("Read() = %q, %v, want no contents", got, err)
+	}
+}
+
+func TestReadCorrupt(t *testing.T) {
+	contents := []byte("EDDF 181220Z 24012KT CAVOK 15/08 Q1013")
+	tests := []struct {
+		name    string
+		corrupt func(data []byte) []byte
+	}{
+		{
+			name: "flipped byte in contents",
+			corrupt: func(data []byte) []byte {
+				data[3] ^= 0x01
+				return data
+			},
+		},
+		{
+			name: "flipped byte in checksum",
+			corrupt: func(data []byte) []byte {
+				data[len(contents)] ^= 0x01
+				return data
+			},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "state")
+			if err := WriteWithChecksum(path, contents); err != nil {
+				t.Fatalf("WriteWithChecksum() error = %v", err)
+			}
+			data, err := os.ReadFile(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := os.WriteFile(path, test.corrupt(data), 0640); err != nil {
+				t.Fatal(err)
+			}
+			if got, err := Read(path); !errors.Is(err, ErrChecksumMismatch) {
+				t.Errorf("Read() = %q, %v, want %v", got, err, ErrChecksumMismatch)
+			}
+		})
+	}
+}
+
+func TestReadWithoutFooter(t *testing.T) {
+	contents := []byte("EDDF 181220Z 24012KT CAVOK 15/08 Q1013")
+	tests := []struct {
+		name string
+		data []byte
+	}{
+		{
+			name: "legacy file",
+			data: contents,
+		},
+		{
+			name: "empty legacy file",
+			data: []byte{},
+		},
+		{
+			name: "truncated footer",
+			data: append(append([]byte(nil), contents...), 0x12, 0x34, 0x56, 0x78, 'S', 'B', 'F', 'C', 'R', 'C', '0'),
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "state")
+			if err := os.WriteFile(path, test.data, 0640); err != nil {
+				t.Fatal(err)
+			}
+			// Files without a complete footer are returned as they are and left to the caller to validate
+			if got, err := Read(path); err != nil || !bytes.Equal(got, test.data) {
+				t.Errorf("Read() = %q, %v, want %q", got, err, test.data)
+			}
+		})
+	}
+}
+
+func TestWriteReplaces(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "nested", "state")
+	for _, contents := range []string{"first", "second"} {
+		if err := Write(path, []byte(contents)); err != nil {
+			t.Fatalf("Write() error = %v", err)
+		}
+		if got, err := Read(path); err != nil || string(got) != contents {
+			t.Errorf("Read() = %q, %v, want %q", got, err, contents)
+		}
+	}
+	entries, err := os.ReadDir(filepath.Dir(path))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 1 {
+		t.Errorf("directory holds %d files, want only the written one", len(entries))
+	}
+	if _, err := Read(dir); err == nil {
+		t.Error("Read() of a directory did not fail")
+	}
+}
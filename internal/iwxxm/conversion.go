@@ -0,0 +1,347 @@
+package iwxxm
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+	"time"
+)
+
+// ErrUnsupported is wrapped by the errors returned for constructs that can not be represented in the traditional
+// alphanumeric code
+var ErrUnsupported = errors.New("construct can not be represented in TAC")
+
+// ErrNilReport is returned for reports that are marked as missing
+var ErrNilReport = errors.New("report is marked as missing")
+
+func unsupportedError(format string, args ...any) error {
+	return fmt.Errorf("%w: %s", ErrUnsupported, fmt.Sprintf(format, args...))
+}
+
+// toTAC renders a report into the traditional alphanumeric code, laid out like the reports in the NOAA cycle files
+// (station first, no METAR/SPECI keyword)
+func toTAC(rep *report) (string, error) {
+	status := rep.ReportStatus
+	if status == "" {
+		status = rep.Status
+	}
+	if status == "MISSING" || rep.Observation.NilReason != "" {
+		return "", ErrNilReport
+	}
+
+	var groups []string
+
+	// Station
+	station := rep.Aerodrome.LocationIndicator
+	if station == "" {
+		station = rep.Aerodrome.Designator
+	}
+	if len(station) != 4 {
+		return "", fmt.Errorf("invalid aerodrome location indicator '%s'", station)
+	}
+	groups = append(groups, station)
+
+	// Observation time
+	rawTime := rep.ObservationTime
+	if rawTime == "" {
+		rawTime = rep.IssueTime
+	}
+	observed, err := time.Parse(time.RFC3339, strings.TrimSpace(rawTime))
+	if err != nil {
+		return "", fmt.Errorf("invalid observation time: %w", err)
+	}
+	groups = append(groups, observed.UTC().Format("021504")+"Z")
+
+	// Report modifiers
+	if status == "CORRECTION" || status == "COR" {
+		groups = append(groups, "COR")
+	}
+	if rep.AutomatedStation {
+		groups = append(groups, "AUTO")
+	}
+
+	obs := rep.Observation.Value
+	if len(obs.Unsupported) > 0 {
+		names := make([]string, 0, len(obs.Unsupported))
+		for _, element := range obs.Unsupported {
+			names = append(names, element.XMLName.Local)
+		}
+		return "", unsupportedError("observation elements %s", strings.Join(names, ", "))
+	}
+
+	// Wind
+	if obs.SurfaceWind != nil {
+		wind, err := renderWind(obs.SurfaceWind)
+		if err != nil {
+			return "", err
+		}
+		groups = append(groups, wind...)
+	}
+
+	// Visibility, RVR, present weather and clouds are replaced by CAVOK if applicable
+	if obs.CloudAndVisibilityOK {
+		groups = append(groups, "CAVOK")
+	} else {
+		if obs.Visibility != nil {
+			vis, err := renderVisibility(obs.Visibility)
+			if err != nil {
+				return "", err
+			}
+			groups = append(groups, vis...)
+		}
+		for _, rvr := range obs.RVRs {
+			group, err := renderRVR(&rvr)
+			if err != nil {
+				return "", err
+			}
+			groups = append(groups, group)
+		}
+		for _, weather := range obs.PresentWeather {
+			if code := codeOf(weather); code != "" {
+				groups = append(groups, code)
+			}
+		}
+		if obs.Cloud != nil {
+			clouds, err := renderClouds(obs.Cloud)
+			if err != nil {
+				return "", err
+			}
+			groups = append(groups, clouds...)
+		}
+	}
+
+	// Temperature and dew point
+	if obs.AirTemperature != nil && obs.DewpointTemperature != nil {
+		air, err := renderTemperature(obs.AirTemperature)
+		if err != nil {
+			return "", err
+		}
+		dew, err := renderTemperature(obs.DewpointTemperature)
+		if err != nil {
+			return "", err
+		}
+		groups = append(groups, air+"/"+dew)
+	}
+
+	// Pressure
+	if obs.QNH != nil && obs.QNH.NilReason == "" {
+		switch obs.QNH.UOM {
+		case "hPa":
+			groups = append(groups, fmt.Sprintf("Q%04d", int(math.Floor(obs.QNH.Value))))
+		case "[in_i'Hg]":
+			groups = append(groups, fmt.Sprintf("A%04d", int(math.Round(obs.QNH.Value*100))))
+		default:
+			return "", unsupportedError("QNH unit '%s'", obs.QNH.UOM)
+		}
+	}
+
+	// Recent weather
+	for _, weather := range obs.RecentWeather {
+		if code := codeOf(weather); code != "" {
+			groups = append(groups, "RE"+code)
+		}
+	}
+
+	// Trend; only the absence of significant changes can be represented
+	for _, trend := range rep.TrendForecasts {
+		if strings.Contains(trend.NilReason, "noSignificantChange") {
+			groups = append(groups, "NOSIG")
+			continue
+		}
+		return "", unsupportedError("trend forecasts")
+	}
+
+	return strings.Join(groups, " "), nil
+}
+
+func renderWind(wind *surfaceWind) ([]string, error) {
+	if wind.MeanWindSpeed == nil {
+		return nil, nil
+	}
+
+	var unit string
+	switch wind.MeanWindSpeed.UOM {
+	case "[kn_i]":
+		unit = "KT"
+	case "m/s":
+		unit = "MPS"
+	default:
+		return nil, unsupportedError("wind speed unit '%s'", wind.MeanWindSpeed.UOM)
+	}
+
+	speed := int(math.Round(wind.MeanWindSpeed.Value))
+	direction := "VRB"
+	if !wind.VariableWindDirection && wind.MeanWindDirection != nil {
+		direction = fmt.Sprintf("%03d", roundTo(wind.MeanWindDirection.Value, 10)%360)
+		if direction == "000" {
+			direction = "360"
+		}
+	}
+	if speed == 0 {
+		direction = "000"
+	}
+
+	group := direction + formatSpeed(speed)
+	if wind.WindGustSpeed != nil && wind.WindGustSpeed.NilReason == "" {
+		group += "G" + formatSpeed(int(math.Round(wind.WindGustSpeed.Value)))
+	}
+	groups := []string{group + unit}
+
+	if wind.ExtremeCounterClockwiseDirection != nil && wind.ExtremeClockwiseWindDirection != nil {
+		groups = append(groups, fmt.Sprintf("%03dV%03d",
+			roundTo(wind.ExtremeCounterClockwiseDirection.Value, 10),
+			roundTo(wind.ExtremeClockwiseWindDirection.Value, 10)))
+	}
+	return groups, nil
+}
+
+func formatSpeed(speed int) string {
+	if speed >= 100 {
+		return "P99"
+	}
+	return fmt.Sprintf("%02d", speed)
+}
+
+func renderVisibility(vis *visibility) ([]string, error) {
+	if vis.PrevailingVisibility == nil || vis.PrevailingVisibility.NilReason != "" {
+		return []string{"////"}, nil
+	}
+	if vis.PrevailingVisibility.UOM != "m" {
+		return nil, unsupportedError("visibility unit '%s'", vis.PrevailingVisibility.UOM)
+	}
+
+	groups := []string{formatVisibility(vis.PrevailingVisibility.Value)}
+	if vis.MinimumVisibility != nil && vis.MinimumVisibility.NilReason == "" {
+		group := formatVisibility(vis.MinimumVisibility.Value)
+		if vis.MinimumVisibilityDirection != nil {
+			group += compassDirection(vis.MinimumVisibilityDirection.Value)
+		}
+		groups = append(groups, group)
+	}
+	return groups, nil
+}
+
+// formatVisibility rounds down a visibility in meters to the reporting steps of the traditional alphanumeric code
+func formatVisibility(meters float64) string {
+	value := int(meters)
+	switch {
+	case value >= 10000:
+		return "9999"
+	case value >= 5000:
+		value = value / 1000 * 1000
+	case value >= 800:
+		value = value / 100 * 100
+	default:
+		value = value / 50 * 50
+	}
+	return fmt.Sprintf("%04d", value)
+}
+
+func compassDirection(degrees float64) string {
+	directions := []string{"N", "NE", "E", "SE", "S", "SW", "W", "NW"}
+	return directions[int(math.Round(math.Mod(degrees, 360)/45))%8]
+}
+
+func renderRVR(rvr *rvr) (string, error) {
+	if rvr.Runway == "" || rvr.MeanRVR == nil {
+		return "", unsupportedError("runway visual range without runway or mean value")
+	}
+	if rvr.MeanRVR.UOM != "m" {
+		return "", unsupportedError("runway visual range unit '%s'", rvr.MeanRVR.UOM)
+	}
+
+	group := "R" + rvr.Runway + "/"
+	switch rvr.Operator {
+	case "ABOVE":
+		group += "P"
+	case "BELOW":
+		group += "M"
+	}
+	group += fmt.Sprintf("%04d", int(rvr.MeanRVR.Value))
+	switch rvr.PastTendency {
+	case "UPWARD":
+		group += "U"
+	case "DOWNWARD":
+		group += "D"
+	case "NO_CHANGE":
+		group += "N"
+	}
+	return group, nil
+}
+
+func renderClouds(cloud *cloud) ([]string, error) {
+	switch {
+	case strings.Contains(cloud.NilReason, "notDetectedByAutoSystem"):
+		return []string{"NCD"}, nil
+	case strings.Contains(cloud.NilReason, "nothingOfOperationalSignificance"):
+		return []string{"NSC"}, nil
+	case cloud.NilReason != "":
+		return nil, nil
+	}
+
+	if cloud.VerticalVisibility != nil {
+		height, err := hundredsOfFeet(cloud.VerticalVisibility)
+		if err != nil {
+			return nil, err
+		}
+		return []string{"VV" + height}, nil
+	}
+
+	groups := make([]string, 0, len(cloud.Layers))
+	for _, layer := range cloud.Layers {
+		amount := codeOf(layer.Amount)
+		if amount == "" {
+			amount = "///"
+		}
+		height := "///"
+		if layer.Base != nil && layer.Base.NilReason == "" {
+			var err error
+			height, err = hundredsOfFeet(layer.Base)
+			if err != nil {
+				return nil, err
+			}
+		}
+		groups = append(groups, amount+height+codeOf(layer.CloudType))
+	}
+	return groups, nil
+}
+
+func hundredsOfFeet(height *measure) (string, error) {
+	var feet float64
+	switch height.UOM {
+	case "[ft_i]":
+		feet = height.Value
+	case "m":
+		feet = height.Value * 3.28084
+	default:
+		return "", unsupportedError("height unit '%s'", height.UOM)
+	}
+	return fmt.Sprintf("%03d", int(math.Round(feet/100))), nil
+}
+
+func renderTemperature(temperature *measure) (string, error) {
+	if temperature.NilReason != "" {
+		return "//", nil
+	}
+	if temperature.UOM != "Cel" {
+		return "", unsupportedError("temperature unit '%s'", temperature.UOM)
+	}
+	value := int(math.Round(temperature.Value))
+	if value < 0 || (value == 0 && math.Signbit(temperature.Value)) {
+		return fmt.Sprintf("M%02d", -value), nil
+	}
+	return fmt.Sprintf("%02d", value), nil
+}
+
+// codeOf extracts the code out of a WMO code registry reference, e.g. http://codes.wmo.int/306/4678/-RA -> -RA
+func codeOf(ref reference) string {
+	if ref.Href == "" || ref.NilReason != "" {
+		return ""
+	}
+	return ref.Href[strings.LastIndex(ref.Href, "/")+1:]
+}
+
+func roundTo(value float64, step int) int {
+	return int(math.Round(value/float64(step))) * step
+}
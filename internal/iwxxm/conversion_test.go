@@ -0,0 +1,265 @@
+package iwxxm
+
+import (
+	"bufio"
+	"encoding/xml"
+	"errors"
+	"github.com/skybi/nuntius/internal/source"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func parseFixture(t *testing.T, name string) ([]*Report, []error) {
+	t.Helper()
+	fixture, err := os.Open(filepath.Join("testdata", name))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer fixture.Close()
+
+	reports, errs, err := Parse(fixture)
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+	return reports, errs
+}
+
+func assertTexts(t *testing.T, reports []*Report, want ...string) {
+	t.Helper()
+	got := make([]string, 0, len(reports))
+	for _, report := range reports {
+		got = append(got, source.WithKeyword(report.Keyword, report.Raw))
+	}
+	if strings.Join(got, "|") != strings.Join(want, "|") {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
+
+func TestParseMETAR(t *testing.T) {
+	reports, errs := parseFixture(t, "metar.xml")
+	if len(errs) > 0 {
+		t.Fatalf("Parse() report errors = %v", errs)
+	}
+	assertTexts(t, reports,
+		"EDDF 181220Z 24012G25KT 210V270 4600 1200NE R25L/P0600U -RA FEW015CB BKN030 M00/M02 Q1013 RETSRA NOSIG")
+	if reports[0].Keyword != "METAR" {
+		t.Errorf("keyword = %s, want METAR", reports[0].Keyword)
+	}
+}
+
+func TestParseSPECI(t *testing.T) {
+	reports, errs := parseFixture(t, "speci.xml")
+	if len(errs) > 0 {
+		t.Fatalf("Parse() report errors = %v", errs)
+	}
+	assertTexts(t, reports, "SPECI KJFK 181251Z COR AUTO VRB03KT 0400 FG VV002 08/08 A2992")
+	if reports[0].Raw != "KJFK 181251Z COR AUTO VRB03KT 0400 FG VV002 08/08 A2992" {
+		t.Errorf("raw = %q, want it without keyword", reports[0].Raw)
+	}
+}
+
+func TestParseCollection(t *testing.T) {
+	reports, errs := parseFixture(t, "collection.xml")
+	assertTexts(t, reports,
+		"LOWW 181220Z 36005MPS CAVOK 10/05 Q1020",
+		"LOWS 181220Z AUTO 00000KT 9999 NCD 09/04 Q1019",
+		"LOWG 181220Z 230P99GP99KT 6000 NSC 11/06 Q1018",
+	)
+	if len(errs) != 2 {
+		t.Fatalf("Parse() report errors = %v, want 2", errs)
+	}
+	if !errors.Is(errs[0], ErrUnsupported) || !strings.Contains(errs[0].Error(), "LOWI") {
+		t.Errorf("error of the report with trend = %v, want %v", errs[0], ErrUnsupported)
+	}
+	if !errors.Is(errs[1], ErrNilReport) || !strings.Contains(errs[1].Error(), "LOWK") {
+		t.Errorf("error of the missing report = %v, want %v", errs[1], ErrNilReport)
+	}
+}
+
+func TestExtract(t *testing.T) {
+	fixture, err := os.Open(filepath.Join("testdata", "speci.xml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer fixture.Close()
+
+	reports, err := Extract(bufio.NewReader(fixture))
+	if err != nil {
+		t.Fatalf("Extract() error = %v", err)
+	}
+	if got, want := reports.ToSlice(), "SPECI KJFK 181251Z COR AUTO VRB03KT 0400 FG VV002 08/08 A2992"; len(got) != 1 || got[0] != want {
+		t.Errorf("Extract() = %q, want [%q]", got, want)
+	}
+
+	// Missing reports are skipped without an error
+	missing := `<METAR reportStatus="NORMAL"><observation nilReason="missing"/></METAR>`
+	if reports, err := Extract(bufio.NewReader(strings.NewReader(missing))); err != nil || reports.Size() != 0 {
+		t.Errorf("Extract() of a missing report = %v, %v, want no reports", reports, err)
+	}
+
+	// Documents only containing reports that can not be converted are an error
+	unsupported := `<METAR reportStatus="NORMAL"><observation nilReason="missing"/></METAR>` +
+		`<SPECI reportStatus="NORMAL"><issueTime><TimeInstant><timePosition>2026-10-18T12:51:00Z</timePosition>` +
+		`</TimeInstant></issueTime><aerodrome><AirportHeliport><timeSlice><AirportHeliportTimeSlice>` +
+		`<locationIndicatorICAO>KJFK</locationIndicatorICAO></AirportHeliportTimeSlice></timeSlice></AirportHeliport>` +
+		`</aerodrome><observation><MeteorologicalAerodromeObservation><windShear/>` +
+		`</MeteorologicalAerodromeObservation></observation></SPECI>`
+	if _, err := Extract(bufio.NewReader(strings.NewReader(unsupported))); !errors.Is(err, ErrUnsupported) {
+		t.Errorf("Extract() of an unsupported report error = %v, want %v", err, ErrUnsupported)
+	}
+}
+
+func TestToTACUnsupported(t *testing.T) {
+	tests := []struct {
+		name   string
+		modify func(rep *report)
+	}{
+		{
+			name: "wind speed unit",
+			modify: func(rep *report) {
+				rep.Observation.Value.SurfaceWind.MeanWindSpeed.UOM = "km/h"
+			},
+		},
+		{
+			name: "visibility unit",
+			modify: func(rep *report) {
+				rep.Observation.Value.Visibility.PrevailingVisibility.UOM = "[ft_i]"
+			},
+		},
+		{
+			name: "runway visual range without runway",
+			modify: func(rep *report) {
+				rep.Observation.Value.RVRs[0].Runway = ""
+			},
+		},
+		{
+			name: "runway visual range unit",
+			modify: func(rep *report) {
+				rep.Observation.Value.RVRs[0].MeanRVR.UOM = "[ft_i]"
+			},
+		},
+		{
+			name: "cloud base unit",
+			modify: func(rep *report) {
+				rep.Observation.Value.Cloud.Layers[1].Base.UOM = "[mi_i]"
+			},
+		},
+		{
+			name: "temperature unit",
+			modify: func(rep *report) {
+				rep.Observation.Value.AirTemperature.UOM = "[degF]"
+			},
+		},
+		{
+			name: "QNH unit",
+			modify: func(rep *report) {
+				rep.Observation.Value.QNH.UOM = "mm[Hg]"
+			},
+		},
+		{
+			name: "observation element",
+			modify: func(rep *report) {
+				rep.Observation.Value.Unsupported = []unsupported{{}}
+			},
+		},
+		{
+			name: "trend forecast",
+			modify: func(rep *report) {
+				rep.TrendForecasts = []trend{{}}
+			},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			data, err := os.ReadFile(filepath.Join("testdata", "metar.xml"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			rep := new(report)
+			if err := xml.Unmarshal(data, rep); err != nil {
+				t.Fatal(err)
+			}
+			if _, err := toTAC(rep); err != nil {
+				t.Fatalf("toTAC() of the unmodified fixture error = %v", err)
+			}
+			test.modify(rep)
+			if raw, err := toTAC(rep); !errors.Is(err, ErrUnsupported) {
+				t.Errorf("toTAC() = %q, %v, want %v", raw, err, ErrUnsupported)
+			}
+		})
+	}
+}
+
+func TestRenderWind(t *testing.T) {
+	tests := []struct {
+		name      string
+		direction float64
+		variable  bool
+		speed     float64
+		gust      float64
+		want      string
+	}{
+		{name: "rounded direction", direction: 243, speed: 12, want: "24012KT"},
+		{name: "north", direction: 356, speed: 7, want: "36007KT"},
+		{name: "calm", direction: 120, speed: 0.4, want: "00000KT"},
+		{name: "variable", variable: true, speed: 2, want: "VRB02KT"},
+		{name: "gust", direction: 90, speed: 20, gust: 31, want: "09020G31KT"},
+		{name: "above 99", direction: 270, speed: 100, gust: 130, want: "270P99GP99KT"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			wind := &surfaceWind{
+				VariableWindDirection: test.variable,
+				MeanWindDirection:     &measure{UOM: "deg", Value: test.direction},
+				MeanWindSpeed:         &measure{UOM: "[kn_i]", Value: test.speed},
+			}
+			if test.gust > 0 {
+				wind.WindGustSpeed = &measure{UOM: "[kn_i]", Value: test.gust}
+			}
+			groups, err := renderWind(wind)
+			if err != nil || strings.Join(groups, " ") != test.want {
+				t.Errorf("renderWind() = %q, %v, want %q", groups, err, test.want)
+			}
+		})
+	}
+}
+
+func TestFormatVisibility(t *testing.T) {
+	tests := map[float64]string{
+		0:     "0000",
+		49:    "0000",
+		420:   "0400",
+		799:   "0750",
+		800:   "0800",
+		4650:  "4600",
+		4999:  "4900",
+		5000:  "5000",
+		9999:  "9000",
+		10000: "9999",
+		25000: "9999",
+	}
+	for meters, want := range tests {
+		if got := formatVisibility(meters); got != want {
+			t.Errorf("formatVisibility(%v) = %s, want %s", meters, got, want)
+		}
+	}
+}
+
+func TestRenderTemperature(t *testing.T) {
+	tests := map[float64]string{
+		-12.5: "M13",
+		-0.5:  "M01",
+		-0.3:  "M00",
+		0:     "00",
+		0.4:   "00",
+		9.5:   "10",
+		31:    "31",
+	}
+	for celsius, want := range tests {
+		if got, err := renderTemperature(&measure{UOM: "Cel", Value: celsius}); err != nil || got != want {
+			t.Errorf("renderTemperature(%v) = %s, %v, want %s", celsius, got, err, want)
+		}
+	}
+}
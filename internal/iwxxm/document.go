@@ -0,0 +1,96 @@
+package iwxxm
+
+import "encoding/xml"
+
+// The structures below only model the parts of IWXXM METAR/SPECI reports that can be represented in the traditional
+// alphanumeric code following the IWXXM 3 schema. Elements are matched by their local name regardless of their namespace.
+
+type report struct {
+	XMLName          xml.Name
+	ReportStatus     string              `xml:"reportStatus,attr"`
+	Status           string              `xml:"status,attr"`
+	AutomatedStation bool                `xml:"automatedStation,attr"`
+	Aerodrome        aerodrome           `xml:"aerodrome"`
+	IssueTime        string              `xml:"issueTime>TimeInstant>timePosition"`
+	ObservationTime  string              `xml:"observationTime>TimeInstant>timePosition"`
+	Observation      observationProperty `xml:"observation"`
+	TrendForecasts   []trend             `xml:"trendForecast"`
+}
+
+type aerodrome struct {
+	LocationIndicator string `xml:"AirportHeliport>timeSlice>AirportHeliportTimeSlice>locationIndicatorICAO"`
+	Designator        string `xml:"AirportHeliport>timeSlice>AirportHeliportTimeSlice>designator"`
+}
+
+type observationProperty struct {
+	NilReason string      `xml:"nilReason,attr"`
+	Value     observation `xml:"MeteorologicalAerodromeObservation"`
+}
+
+type observation struct {
+	CloudAndVisibilityOK bool          `xml:"cloudAndVisibilityOK,attr"`
+	AirTemperature       *measure      `xml:"airTemperature"`
+	DewpointTemperature  *measure      `xml:"dewpointTemperature"`
+	QNH                  *measure      `xml:"qnh"`
+	SurfaceWind          *surfaceWind  `xml:"surfaceWind>AerodromeSurfaceWind"`
+	Visibility           *visibility   `xml:"visibility>AerodromeHorizontalVisibility"`
+	RVRs                 []rvr         `xml:"rvr>AerodromeRunwayVisualRange"`
+	PresentWeather       []reference   `xml:"presentWeather"`
+	Cloud                *cloud        `xml:"cloud"`
+	RecentWeather        []reference   `xml:"recentWeather"`
+	Unsupported          []unsupported `xml:",any"`
+}
+
+type measure struct {
+	UOM       string  `xml:"uom,attr"`
+	NilReason string  `xml:"nilReason,attr"`
+	Value     float64 `xml:",chardata"`
+}
+
+type reference struct {
+	Href      string `xml:"href,attr"`
+	NilReason string `xml:"nilReason,attr"`
+}
+
+type surfaceWind struct {
+	VariableWindDirection            bool     `xml:"variableWindDirection,attr"`
+	MeanWindDirection                *measure `xml:"meanWindDirection"`
+	MeanWindSpeed                    *measure `xml:"meanWindSpeed"`
+	WindGustSpeed                    *measure `xml:"windGustSpeed"`
+	ExtremeClockwiseWindDirection    *measure `xml:"extremeClockwiseWindDirection"`
+	ExtremeCounterClockwiseDirection *measure `xml:"extremeCounterClockwiseWindDirection"`
+}
+
+type visibility struct {
+	PrevailingVisibility       *measure `xml:"prevailingVisibility"`
+	MinimumVisibility          *measure `xml:"minimumVisibility"`
+	MinimumVisibilityDirection *measure `xml:"minimumVisibilityDirection"`
+}
+
+type rvr struct {
+	PastTendency string   `xml:"pastTendency,attr"`
+	Runway       string   `xml:"runway>RunwayDirection>timeSlice>RunwayDirectionTimeSlice>designator"`
+	MeanRVR      *measure `xml:"meanRVR"`
+	Operator     string   `xml:"meanRVROperator"`
+}
+
+type cloud struct {
+	NilReason          string       `xml:"nilReason,attr"`
+	Layers             []cloudLayer `xml:"AerodromeCloud>layer>CloudLayer"`
+	VerticalVisibility *measure     `xml:"AerodromeCloud>verticalVisibility"`
+}
+
+type cloudLayer struct {
+	Amount    reference `xml:"amount"`
+	Base      *measure  `xml:"base"`
+	CloudType reference `xml:"cloudType"`
+}
+
+type trend struct {
+	NilReason string        `xml:"nilReason,attr"`
+	Content   []unsupported `xml:",any"`
+}
+
+type unsupported struct {
+	XMLName xml.Name
+}
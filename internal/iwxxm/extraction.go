@@ -0,0 +1,87 @@
+package iwxxm
+
+import (
+	"bufio"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"github.com/rs/zerolog/log"
+	"github.com/skybi/nuntius/internal/set"
+	"github.com/skybi/nuntius/internal/source"
+	"io"
+)
+
+// Report represents a single METAR or SPECI rendered into the traditional alphanumeric code
+type Report struct {
+	// Keyword is either METAR or SPECI
+	Keyword string
+	Raw     string
+}
+
+// Parse parses all METAR and SPECI reports contained in an IWXXM document (which may be a single report or a
+// collection of them) and renders them into the traditional alphanumeric code.
+// Reports that can not be represented are skipped and their errors returned alongside.
+func Parse(reader io.Reader) ([]*Report, []error, error) {
+	decoder := xml.NewDecoder(reader)
+
+	var reports []*Report
+	var errs []error
+	for {
+		token, err := decoder.Token()
+		if err != nil {
+			if errors.Is(err, io.EOF) {
+				break
+			}
+			return nil, nil, err
+		}
+
+		start, ok := token.(xml.StartElement)
+		if !ok || (start.Name.Local != "METAR" && start.Name.Local != "SPECI") {
+			continue
+		}
+
+		rep := new(report)
+		if err := decoder.DecodeElement(rep, &start); err != nil {
+			return nil, nil, err
+		}
+		raw, err := toTAC(rep)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s %s: %w", start.Name.Local, rep.Aerodrome.LocationIndicator, err))
+			continue
+		}
+		reports = append(reports, &Report{
+			Keyword: start.Name.Local,
+			Raw:     raw,
+		})
+	}
+	return reports, errs, nil
+}
+
+// Extract extracts and deduplicates the METARs and SPECIs contained in an IWXXM document.
+// SPECIs are prefixed with their keyword.
+// Missing (NIL) reports are skipped like in WMO bulletins. Reports that can not be represented are logged and skipped;
+// an error is only returned if the document is malformed or none of its other reports could be converted.
+func Extract(reader *bufio.Reader) (*set.HashSet[string], error) {
+	reports, errs, err := Parse(reader)
+	if err != nil {
+		return nil, err
+	}
+	var failed []error
+	for _, err := range errs {
+		if errors.Is(err, ErrNilReport) {
+			log.Debug().Err(err).Msg("skipping missing IWXXM report")
+			continue
+		}
+		log.Warn().Err(err).Msg("skipping IWXXM report")
+		failed = append(failed, err)
+	}
+	if len(reports) == 0 && len(failed) > 0 {
+		return nil, failed[0]
+	}
+
+	hashSet := set.NewHashSet[string]()
+	for _, report := range reports {
+		hashSet.Add(source.WithKeyword(report.Keyword, report.Raw))
+	}
+	return hashSet, nil
+}
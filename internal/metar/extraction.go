@@ -0,0 +1,42 @@
+package metar
+
+import (
+	"bufio"
+	"errors"
+	"github.com/skybi/nuntius/internal/set"
+	"io"
+)
+
+// Extract extracts and deduplicates the raw METARs out of a NOAA cycle file, skipping empty and timestamp lines
+func Extract(reader *bufio.Reader) (*set.HashSet[string], error) {
+	hashSet := set.NewHashSet[string]()
+	if reader.Size() == 0 {
+		return hashSet, nil
+	}
+
+	end := false
+	for !end {
+		line, isPrefix, err := reader.ReadLine()
+		if err != nil && !errors.Is(err, io.EOF) {
+			return nil, err
+		}
+		for isPrefix {
+			fragment, isAnotherPrefix, err := reader.ReadLine()
+			if err != nil && !errors.Is(err, io.EOF) {
+				return nil, err
+			}
+			line = append(line, fragment...)
+			isPrefix = isAnotherPrefix
+		}
+		end = err != nil && errors.Is(err, io.EOF)
+
+		// 47 = '/'
+		if len(line) == 0 || (len(line) > 4 && line[4] == 47) {
+			continue
+		}
+
+		hashSet.Add(string(line))
+	}
+
+	return hashSet, nil
+}
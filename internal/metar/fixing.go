@@ -15,8 +15,8 @@ func normalize(r rune) rune {
 	return r
 }
 
-// fix applies fixes on a raw METAR that solve common problems experienced over time
-func fix(raw string) string {
+// Fix applies fixes on a raw METAR that solve common problems experienced over time
+func Fix(raw string) string {
 	runes := []rune(raw)
 	var builder strings.Builder
 
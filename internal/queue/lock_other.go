@@ -0,0 +1,13 @@
+//go:build !(darwin || dragonfly || freebsd || linux || netbsd || openbsd)
+
+package queue
+
+import (
+	"os"
+	"path/filepath"
+)
+
+// lockDir is not supported on this platform, meaning that the lock file is created but not locked
+func lockDir(dir string) (*os.File, error) {
+	return os.OpenFile(filepath.Join(dir, lockFileName), os.O_RDWR|os.O_CREATE, 0640)
+}
@@ -0,0 +1,32 @@
+package queue
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Order describes in which order the entries of a queue are popped
+type Order int
+
+const (
+	// OrderFIFO pops entries in the order they were pushed
+	OrderFIFO Order = iota
+
+	// OrderPriority pops the entries with the highest priority first
+	OrderPriority
+)
+
+// PriorityFunc returns the priority of a value; entries with a higher priority are popped first
+type PriorityFunc[T any] func(value T) int64
+
+// ParseOrder parses a queue order out of its name
+func ParseOrder(raw string) (Order, error) {
+	switch strings.ToLower(strings.TrimSpace(raw)) {
+	case "fifo":
+		return OrderFIFO, nil
+	case "priority":
+		return OrderPriority, nil
+	default:
+		return 0, fmt.Errorf("unknown queue order '%s'", raw)
+	}
+}
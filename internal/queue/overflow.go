@@ -0,0 +1,56 @@
+package queue
+
+import (
+	"fmt"
+	"strings"
+)
+
+// OverflowPolicy describes what happens to reports pushed into a queue that reached its capacity
+type OverflowPolicy int
+
+const (
+	// OverflowBlock blocks pushing until enough entries were popped
+	OverflowBlock OverflowPolicy = iota
+
+	// OverflowDropOldest drops the oldest queued entries to make room for the new ones
+	OverflowDropOldest
+
+	// OverflowDropNewest drops the pushed entries that do not fit into the queue anymore
+	OverflowDropNewest
+
+	// OverflowSpill only keeps entries up to the capacity in memory and loads the others out of the log once there is
+	// room again
+	OverflowSpill
+)
+
+// ParseOverflowPolicy parses an overflow policy out of its name
+func ParseOverflowPolicy(raw string) (OverflowPolicy, error) {
+	switch strings.ToLower(strings.TrimSpace(raw)) {
+	case "block":
+		return OverflowBlock, nil
+	case "drop-oldest":
+		return OverflowDropOldest, nil
+	case "drop-newest":
+		return OverflowDropNewest, nil
+	case "spill":
+		return OverflowSpill, nil
+	default:
+		return 0, fmt.Errorf("unknown overflow policy '%s'", raw)
+	}
+}
+
+// String returns the name of the overflow policy
+func (policy OverflowPolicy) String() string {
+	switch policy {
+	case OverflowBlock:
+		return "block"
+	case OverflowDropOldest:
+		return "drop-oldest"
+	case OverflowDropNewest:
+		return "drop-newest"
+	case OverflowSpill:
+		return "spill"
+	default:
+		return "unknown"
+	}
+}
@@ -0,0 +1,155 @@
+package queue
+
+import (
+	"container/heap"
+	"container/list"
+)
+
+// pendingEntries holds the entries of a write-ahead log queue that were not popped yet
+type pendingEntries[T any] interface {
+	// add adds an entry
+	add(entry *Entry[T])
+
+	// next removes and returns the entry to pop next or nil if there is none
+	next() *Entry[T]
+
+	// oldest removes and returns the entry that was pushed first or nil if there is none
+	oldest() *Entry[T]
+
+	size() int
+	each(fn func(entry *Entry[T]))
+}
+
+// fifoEntries pops entries in the order they were added
+type fifoEntries[T any] struct {
+	entries *list.List
+}
+
+func newFIFOEntries[T any]() *fifoEntries[T] {
+	return &fifoEntries[T]{
+		entries: list.New(),
+	}
+}
+
+func (fifo *fifoEntries[T]) add(entry *Entry[T]) {
+	fifo.entries.PushBack(entry)
+}
+
+func (fifo *fifoEntries[T]) next() *Entry[T] {
+	if fifo.entries.Len() == 0 {
+		return nil
+	}
+	return fifo.entries.Remove(fifo.entries.Front()).(*Entry[T])
+}
+
+func (fifo *fifoEntries[T]) oldest() *Entry[T] {
+	return fifo.next()
+}
+
+func (fifo *fifoEntries[T]) size() int {
+	return fifo.entries.Len()
+}
+
+func (fifo *fifoEntries[T]) each(fn func(entry *Entry[T])) {
+	for elem := fifo.entries.Front(); elem != nil; elem = elem.Next() {
+		fn(elem.Value.(*Entry[T]))
+	}
+}
+
+// priorityEntries pops the entries with the highest priority first while also keeping track of their age
+type priorityEntries[T any] struct {
+	byPriority *entryHeap[T]
+	bySeq      *entryHeap[T]
+}
+
+func newPriorityEntries[T any]() *priorityEntries[T] {
+	return &priorityEntries[T]{
+		byPriority: &entryHeap[T]{
+			less: func(a, b *Entry[T]) bool {
+				if a.priority != b.priority {
+					return a.priority > b.priority
+				}
+				return a.seq < b.seq
+			},
+			index: func(entry *Entry[T]) *int {
+				return &entry.priorityIndex
+			},
+		},
+		bySeq: &entryHeap[T]{
+			less: func(a, b *Entry[T]) bool {
+				return a.seq < b.seq
+			},
+			index: func(entry *Entry[T]) *int {
+				return &entry.seqIndex
+			},
+		},
+	}
+}
+
+func (prio *priorityEntries[T]) add(entry *Entry[T]) {
+	heap.Push(prio.byPriority, entry)
+	heap.Push(prio.bySeq, entry)
+}
+
+func (prio *priorityEntries[T]) next() *Entry[T] {
+	if prio.byPriority.Len() == 0 {
+		return nil
+	}
+	entry := heap.Pop(prio.byPriority).(*Entry[T])
+	heap.Remove(prio.bySeq, entry.seqIndex)
+	return entry
+}
+
+func (prio *priorityEntries[T]) oldest() *Entry[T] {
+	if prio.bySeq.Len() == 0 {
+		return nil
+	}
+	entry := heap.Pop(prio.bySeq).(*Entry[T])
+	heap.Remove(prio.byPriority, entry.priorityIndex)
+	return entry
+}
+
+func (prio *priorityEntries[T]) size() int {
+	return prio.byPriority.Len()
+}
+
+func (prio *priorityEntries[T]) each(fn func(entry *Entry[T])) {
+	for _, entry := range prio.bySeq.entries {
+		fn(entry)
+	}
+}
+
+// entryHeap implements heap.Interface for entries, keeping track of their positions to be able to remove them
+type entryHeap[T any] struct {
+	entries []*Entry[T]
+	less    func(a, b *Entry[T]) bool
+	index   func(entry *Entry[T]) *int
+}
+
+func (h *entryHeap[T]) Len() int {
+	return len(h.entries)
+}
+
+func (h *entryHeap[T]) Less(i, j int) bool {
+	return h.less(h.entries[i], h.entries[j])
+}
+
+func (h *entryHeap[T]) Swap(i, j int) {
+	h.entries[i], h.entries[j] = h.entries[j], h.entries[i]
+	*h.index(h.entries[i]) = i
+	*h.index(h.entries[j]) = j
+}
+
+func (h *entryHeap[T]) Push(value any) {
+	entry := value.(*Entry[T])
+	*h.index(entry) = len(h.entries)
+	h.entries = append(h.entries, entry)
+}
+
+func (h *entryHeap[T]) Pop() any {
+	last := len(h.entries) - 1
+	entry := h.entries[last]
+	h.entries[last] = nil
+	h.entries = h.entries[:last]
+	return entry
+}
@@ -0,0 +1,828 @@
+package queue
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"github.com/fxamacker/cbor/v2"
+	"github.com/skybi/nuntius/internal/file"
+	"hash/crc32"
+	"io"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+const (
+	// DefaultSegmentSize is the size a log segment may grow to before a new one is started
+	DefaultSegmentSize = 16 << 20
+
+	segmentExtension = ".wal"
+	lockFileName     = "lock"
+	frameHeaderSize  = 8
+	maxFrameSize     = 64 << 20
+)
+
+// ErrCorruptSegment is returned when a log segment other than the last one contains an invalid frame
+var ErrCorruptSegment = errors.New("corrupt write-ahead log segment")
+
+// ErrLocked is returned when opening a write-ahead log queue that is already opened by another process
+var ErrLocked = errors.New("write-ahead log queue is locked by another process")
+
+// Options represents the configuration of a write-ahead log queue
+type Options struct {
+	// SegmentSize is the size a log segment may grow to before a new one is started
+	SegmentSize int64
+
+	// Capacity is the maximum amount of queued entries; 0 means unlimited
+	Capacity int
+
+	// Overflow decides what happens when entries are pushed into a full queue
+	Overflow OverflowPolicy
+
+	// Order decides in which order entries are popped
+	Order Order
+
+	// BacklogShare is the share of every popped batch reserved for the oldest entries when using OrderPriority
+	BacklogShare float64
+}
+
+// record represents a single entry of the write-ahead log; it either pushes a value, acknowledges previous pushes or
+// records a failed attempt to process them
+type record[T any] struct {
+	Seq      uint64   `cbor:"1,keyasint,omitempty"`
+	Value    T        `cbor:"2,keyasint"`
+	Acks     []uint64 `cbor:"3,keyasint,omitempty"`
+	Attempts int      `cbor:"4,keyasint,omitempty"`
+	Error    string   `cbor:"5,keyasint,omitempty"`
+	Failures []uint64 `cbor:"6,keyasint,omitempty"`
+}
+
+// Entry represents a queued value together with the failed attempts to process it
+type Entry[T any] struct {
+	Value T
+
+	// Attempts is the amount of failed attempts to process the entry and LastError the reason of the last one
+	Attempts  int
+	LastError string
+
+	seq      uint64
+	size     int64
+	priority int64
+
+	// priorityIndex and seqIndex are the positions of the entry inside the heaps of priorityEntries
+	priorityIndex int
+	seqIndex      int
+}
+
+// spilledEntry holds the failed attempts of an entry that is not kept in memory, as they are not necessarily stored
+// inside its push record
+type spilledEntry struct {
+	attempts  int
+	lastError string
+}
+
+type segment struct {
+	id   uint64
+	size int64
+}
+
+// WAL represents a thread safe FIFO queue persisting every entry into an append-only write-ahead log before accepting
+// it. Popped entries stay in the log until they are acknowledged and are restored if the process dies beforehand.
+// It is meant to be drained by a single consumer popping a batch, processing it and then either acknowledging or
+// requeueing its entries.
+type WAL[T any] struct {
+	sync.RWMutex
+	dir     string
+	options Options
+
+	priority PriorityFunc[T]
+	pending  pendingEntries[T]
+	inFlight map[uint64]*Entry[T]
+	nextSeq  uint64
+	dropped  uint64
+
+	// spilled holds the failed attempts of the entries only stored in the log because the queue was full by their
+	// sequence number
+	spilled map[uint64]spilledEntry
+	space   *sync.Cond
+
+	segments  []*segment
+	active    *os.File
+	logBytes  int64
+	liveBytes int64
+
+	// lock is the lock file keeping other processes from opening the log while it is open
+	lock *os.File
+}
+
+// Open opens the write-ahead log queue stored in dir, creating it if it does not exist, and restores its entries.
+// priority is only used when the queue is ordered by priority.
+// The directory stays locked until the log is closed so that no other process writes to it meanwhile.
+func Open[T any](dir string, options Options, priority PriorityFunc[T]) (*WAL[T], error) {
+	if options.Order == OrderPriority && priority == nil {
+		return nil, errors.New("priority order requires a priority function")
+	}
+	if err := os.MkdirAll(dir, 0750); err != nil {
+		return nil, err
+	}
+	lock, err := lockDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	if options.SegmentSize <= 0 {
+		options.SegmentSize = DefaultSegmentSize
+	}
+	wal := &WAL[T]{
+		dir:      dir,
+		options:  options,
+		priority: priority,
+		inFlight: make(map[uint64]*Entry[T]),
+		spilled:  make(map[uint64]spilledEntry),
+		nextSeq:  1,
+		lock:     lock,
+	}
+	if options.Order == OrderPriority {
+		wal.pending = newPriorityEntries[T]()
+	} else {
+		wal.pending = newFIFOEntries[T]()
+	}
+	wal.space = sync.NewCond(&wal.RWMutex)
+	if err := wal.replay(); err != nil {
+		lock.Close()
+		return nil, err
+	}
+	if err := wal.openActive(); err != nil {
+		lock.Close()
+		return nil, err
+	}
+	return wal, nil
+}
+
+// Size returns the amount of queued entries that were not popped yet
+func (wal *WAL[T]) Size() int {
+	wal.RLock()
+	defer wal.RUnlock()
+	return wal.pending.size() + len(wal.spilled)
+}
+
+// InFlight returns the amount of popped entries that were not acknowledged or requeued yet
+func (wal *WAL[T]) InFlight() int {
+	wal.RLock()
+	defer wal.RUnlock()
+	return len(wal.inFlight)
+}
+
+// Dropped returns the amount of entries dropped because the queue was full since it was opened
+func (wal *WAL[T]) Dropped() uint64 {
+	wal.RLock()
+	defer wal.RUnlock()
+	return wal.dropped
+}
+
+// Push persists entries and pushes them to the back of the queue, applying the overflow policy if it is full.
+// It returns the amount of entries that were dropped.
+func (wal *WAL[T]) Push(values ...T) (int, error) {
+	entries := make([]*Entry[T], 0, len(values))
+	for _, value := range values {
+		entries = append(entries, &Entry[T]{Value: value})
+	}
+	return wal.PushEntries(entries...)
+}
+
+// PushEntries pushes entries like Push but keeps their failed attempts, e.g. to restore entries exported beforehand
+func (wal *WAL[T]) PushEntries(entries ...*Entry[T]) (int, error) {
+	wal.Lock()
+	defer wal.Unlock()
+
+	capacity := wal.options.Capacity
+	if capacity <= 0 || wal.options.Overflow == OverflowSpill || wal.options.Overflow == OverflowDropOldest {
+		return wal.push(entries)
+	}
+
+	switch wal.options.Overflow {
+	case OverflowBlock:
+		for len(entries) > 0 {
+			for wal.active != nil && wal.queued() >= capacity {
+				wal.space.Wait()
+			}
+			if wal.active == nil {
+				return 0, os.ErrClosed
+			}
+			n := capacity - wal.queued()
+			if n > len(entries) {
+				n = len(entries)
+			}
+			if _, err := wal.push(entries[:n]); err != nil {
+				return 0, err
+			}
+			entries = entries[n:]
+		}
+		return 0, nil
+	default:
+		n := capacity - wal.queued()
+		if n < 0 {
+			n = 0
+		}
+		if n > len(entries) {
+			n = len(entries)
+		}
+		dropped := len(entries) - n
+		wal.dropped += uint64(dropped)
+		_, err := wal.push(entries[:n])
+		return dropped, err
+	}
+}
+
+// queued returns the amount of entries occupying the queue's capacity
+func (wal *WAL[T]) queued() int {
+	return wal.pending.size() + len(wal.inFlight) + len(wal.spilled)
+}
+
+func (wal *WAL[T]) push(pushed []*Entry[T]) (int, error) {
+	if len(pushed) == 0 {
+		return 0, nil
+	}
+	if wal.active == nil {
+		return 0, os.ErrClosed
+	}
+
+	var buffer []byte
+	entries := make([]*Entry[T], 0, len(pushed))
+	for _, entry := range pushed {
+		frame, err := encodeFrame(&record[T]{
+			Seq:      wal.nextSeq,
+			Value:    entry.Value,
+			Attempts: entry.Attempts,
+			Error:    entry.LastError,
+		})
+		if err != nil {
+			return 0, err
+		}
+		buffer = append(buffer, frame...)
+		entries = append(entries, &Entry[T]{
+			Value:     entry.Value,
+			Attempts:  entry.Attempts,
+			LastError: entry.LastError,
+			seq:       wal.nextSeq,
+			size:      int64(len(frame)),
+		})
+		wal.nextSeq++
+	}
+	if err := wal.append(buffer); err != nil {
+		return 0, err
+	}
+
+	for _, entry := range entries {
+		wal.liveBytes += entry.size
+		if wal.options.Overflow == OverflowSpill && wal.options.Capacity > 0 &&
+			(len(wal.spilled) > 0 || wal.pending.size()+len(wal.inFlight) >= wal.options.Capacity) {
+			wal.spilled[entry.seq] = spilledEntry{attempts: entry.Attempts, lastError: entry.LastError}
+			continue
+		}
+		wal.load(entry)
+	}
+
+	dropped := 0
+	if wal.options.Overflow == OverflowDropOldest && wal.options.Capacity > 0 {
+		var err error
+		dropped, err = wal.dropOldest()
+		if err != nil {
+			return 0, err
+		}
+	}
+	return dropped, wal.rollIfFull()
+}
+
+// dropOldest acknowledges the oldest queued entries exceeding the capacity
+func (wal *WAL[T]) dropOldest() (int, error) {
+	var drop []*Entry[T]
+	for wal.queued() > wal.options.Capacity {
+		entry := wal.pending.oldest()
+		if entry == nil {
+			break
+		}
+		drop = append(drop, entry)
+	}
+	if err := wal.ack(drop); err != nil {
+		return 0, err
+	}
+	wal.dropped += uint64(len(drop))
+	return len(drop), nil
+}
+
+// PopN pops the first n entries of the queue; each of them has to be acknowledged or requeued afterwards.
+// When ordered by priority, the backlog share of them are the oldest entries and the others the ones with the highest
+// priority.
+func (wal *WAL[T]) PopN(n int) []*Entry[T] {
+	wal.Lock()
+	defer wal.Unlock()
+
+	backlog := 0
+	if wal.options.Order == OrderPriority && wal.options.BacklogShare > 0 {
+		backlog = int(math.Ceil(float64(n) * wal.options.BacklogShare))
+	}
+
+	entries := make([]*Entry[T], 0, n)
+	for i := 0; i < n; i++ {
+		var entry *Entry[T]
+		if i < backlog {
+			entry = wal.pending.oldest()
+		} else {
+			entry = wal.pending.next()
+		}
+		if entry == nil {
+			break
+		}
+		wal.inFlight[entry.seq] = entry
+		entries = append(entries, entry)
+	}
+	return entries
+}
+
+// Ack acknowledges popped entries, removing them from the log
+func (wal *WAL[T]) Ack(entries ...*Entry[T]) error {
+	wal.Lock()
+	defer wal.Unlock()
+
+	entries = wal.popped(entries)
+	if len(entries) == 0 {
+		return nil
+	}
+	if err := wal.ack(entries); err != nil {
+		return err
+	}
+	for _, entry := range entries {
+		delete(wal.inFlight, entry.seq)
+	}
+	wal.space.Broadcast()
+
+	// Load spilled entries into memory again once half of the capacity is free
+	if len(wal.spilled) > 0 && wal.pending.size() <= wal.options.Capacity/2 {
+		if err := wal.loadSpilled(wal.options.Capacity - wal.pending.size()); err != nil {
+			return err
+		}
+	}
+
+	// Rewrite the log if most of it consists of acknowledged entries
+	if wal.logBytes >= wal.options.SegmentSize && wal.logBytes >= 2*wal.liveBytes {
+		return wal.compact()
+	}
+	return wal.rollIfFull()
+}
+
+func (wal *WAL[T]) ack(entries []*Entry[T]) error {
+	if len(entries) == 0 {
+		return nil
+	}
+	frame, err := encodeFrame(&record[T]{
+		Acks: seqsOf(entries),
+	})
+	if err != nil {
+		return err
+	}
+	if err := wal.append(frame); err != nil {
+		return err
+	}
+	for _, entry := range entries {
+		wal.liveBytes -= entry.size
+	}
+	return nil
+}
+
+// Fail records a failed attempt to process popped entries, incrementing their attempts. The entries stay popped and
+// still have to be acknowledged or requeued afterwards.
+func (wal *WAL[T]) Fail(reason string, entries ...*Entry[T]) error {
+	wal.Lock()
+	defer wal.Unlock()
+
+	entries = wal.popped(entries)
+	if len(entries) == 0 {
+		return nil
+	}
+	frame, err := encodeFrame(&record[T]{
+		Failures: seqsOf(entries),
+		Error:    reason,
+	})
+	if err != nil {
+		return err
+	}
+	if err := wal.append(frame); err != nil {
+		return err
+	}
+	for _, entry := range entries {
+		entry.Attempts++
+		entry.LastError = reason
+	}
+	return wal.rollIfFull()
+}
+
+// Requeue pushes popped entries to the back of the queue again
+func (wal *WAL[T]) Requeue(entries ...*Entry[T]) {
+	wal.Lock()
+	defer wal.Unlock()
+	for _, entry := range wal.popped(entries) {
+		delete(wal.inFlight, entry.seq)
+		wal.pending.add(entry)
+	}
+}
+
+// popped filters out the entries that are not popped (anymore)
+func (wal *WAL[T]) popped(entries []*Entry[T]) []*Entry[T] {
+	filtered := make([]*Entry[T], 0, len(entries))
+	for _, entry := range entries {
+		if wal.inFlight[entry.seq] == entry {
+			filtered = append(filtered, entry)
+		}
+	}
+	return filtered
+}
+
+func seqsOf[T any](entries []*Entry[T]) []uint64 {
+	seqs := make([]uint64, 0, len(entries))
+	for _, entry := range entries {
+		seqs = append(seqs, entry.seq)
+	}
+	return seqs
+}
+
+// load adds an entry to the in-memory entries
+func (wal *WAL[T]) load(entry *Entry[T]) {
+	if wal.priority != nil && wal.options.Order == OrderPriority {
+		entry.priority = wal.priority(entry.Value)
+	}
+	wal.pending.add(entry)
+}
+
+// Close closes the log, unblocking pending pushes; entries that were popped but not acknowledged are restored when
+// opening it again
+func (wal *WAL[T]) Close() error {
+	wal.Lock()
+	defer wal.Unlock()
+	if wal.active == nil {
+		return nil
+	}
+	err := wal.active.Close()
+	wal.active = nil
+	wal.space.Broadcast()
+	if lockErr := wal.lock.Close(); err == nil {
+		err = lockErr
+	}
+	return err
+}
+
+// append writes data to the active segment and flushes it to disk
+func (wal *WAL[T]) append(data []byte) error {
+	if wal.active == nil {
+		return os.ErrClosed
+	}
+	if _, err := wal.active.Write(data); err != nil {
+		return err
+	}
+	if err := wal.active.Sync(); err != nil {
+		return err
+	}
+	wal.segments[len(wal.segments)-1].size += int64(len(data))
+	wal.logBytes += int64(len(data))
+	return nil
+}
+
+// rollIfFull starts a new segment if the active one exceeded the segment size
+func (wal *WAL[T]) rollIfFull() error {
+	if wal.segments[len(wal.segments)-1].size < wal.options.SegmentSize {
+		return nil
+	}
+	if err := wal.active.Close(); err != nil {
+		return err
+	}
+	wal.active = nil
+	return wal.createSegment(wal.segments[len(wal.segments)-1].id + 1)
+}
+
+// loadSpilled loads up to n spilled entries out of the log into memory
+func (wal *WAL[T]) loadSpilled(n int) error {
+	err := wal.scan(wal.segments, func(rec *record[T], size int64) (bool, error) {
+		spilled, ok := wal.spilled[rec.Seq]
+		if !ok {
+			return true, nil
+		}
+		delete(wal.spilled, rec.Seq)
+		wal.load(&Entry[T]{
+			Value:     rec.Value,
+			Attempts:  spilled.attempts,
+			LastError: spilled.lastError,
+			seq:       rec.Seq,
+			size:      size,
+		})
+		n--
+		return n > 0 && len(wal.spilled) > 0, nil
+	})
+	if err != nil {
+		return err
+	}
+
+	// Forget spilled entries whose records are missing from the log so that they are not waited for forever
+	if n > 0 && len(wal.spilled) > 0 {
+		wal.spilled = make(map[uint64]spilledEntry)
+	}
+	return nil
+}
+
+// compact writes every live entry into a new segment and removes all previous ones
+func (wal *WAL[T]) compact() error {
+	var buffer []byte
+	live := make([]*Entry[T], 0, len(wal.inFlight)+wal.pending.size())
+	for _, entry := range wal.inFlight {
+		live = append(live, entry)
+	}
+	sort.Slice(live, func(i, j int) bool {
+		return live[i].seq < live[j].seq
+	})
+	wal.pending.each(func(entry *Entry[T]) {
+		live = append(live, entry)
+	})
+	for _, entry := range live {
+		frame, err := encodeFrame(&record[T]{
+			Seq:      entry.seq,
+			Value:    entry.Value,
+			Attempts: entry.Attempts,
+			Error:    entry.LastError,
+		})
+		if err != nil {
+			return err
+		}
+		buffer = append(buffer, frame...)
+	}
+
+	old := wal.segments
+	if err := wal.active.Close(); err != nil {
+		return err
+	}
+	wal.active = nil
+	wal.segments = nil
+	wal.logBytes = 0
+	if err := wal.createSegment(old[len(old)-1].id + 1); err != nil {
+		return err
+	}
+	if err := wal.append(buffer); err != nil {
+		return err
+	}
+
+	// Copy the spilled entries which are only stored in the old segments
+	if len(wal.spilled) > 0 {
+		copied := make(map[uint64]struct{}, len(wal.spilled))
+		err := wal.scan(old, func(rec *record[T], _ int64) (bool, error) {
+			spilled, ok := wal.spilled[rec.Seq]
+			if _, done := copied[rec.Seq]; !ok || done {
+				return true, nil
+			}
+			copied[rec.Seq] = struct{}{}
+			frame, err := encodeFrame(&record[T]{
+				Seq:      rec.Seq,
+				Value:    rec.Value,
+				Attempts: spilled.attempts,
+				Error:    spilled.lastError,
+			})
+			if err != nil {
+				return false, err
+			}
+			return true, wal.append(frame)
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	// Remove the old segments oldest first so that an interrupted compaction never loses acknowledgements
+	for _, seg := range old {
+		if err := os.Remove(wal.segmentPath(seg.id)); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return err
+		}
+	}
+	return file.SyncDir(wal.dir)
+}
+
+// scan calls fn for every push record stored in the given segments until it returns false or an error
+func (wal *WAL[T]) scan(segments []*segment, fn func(rec *record[T], size int64) (bool, error)) error {
+	for _, seg := range segments {
+		more, err := wal.scanSegment(seg, fn)
+		if err != nil || !more {
+			return err
+		}
+	}
+	return nil
+}
+
+func (wal *WAL[T]) scanSegment(seg *segment, fn func(rec *record[T], size int64) (bool, error)) (bool, error) {
+	segmentFile, err := os.Open(wal.segmentPath(seg.id))
+	if err != nil {
+		return false, err
+	}
+	defer segmentFile.Close()
+
+	reader := bufio.NewReader(io.LimitReader(segmentFile, seg.size))
+	for {
+		rec, size, err := readFrame[T](reader)
+		if err != nil {
+			if errors.Is(err, io.EOF) {
+				return true, nil
+			}
+			return false, err
+		}
+		if rec.Seq == 0 {
+			continue
+		}
+		more, err := fn(rec, size)
+		if err != nil || !more {
+			return false, err
+		}
+	}
+}
+
+func (wal *WAL[T]) segmentPath(id uint64) string {
+	return filepath.Join(wal.dir, fmt.Sprintf("%016d%s", id, segmentExtension))
+}
+
+func (wal *WAL[T]) createSegment(id uint64) error {
+	segmentFile, err := os.OpenFile(wal.segmentPath(id), os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0640)
+	if err != nil {
+		return err
+	}
+	if err := file.SyncDir(wal.dir); err != nil {
+		segmentFile.Close()
+		return err
+	}
+	wal.active = segmentFile
+	wal.segments = append(wal.segments, &segment{
+		id: id,
+	})
+	return nil
+}
+
+// openActive opens the last segment for appending or creates the first one
+func (wal *WAL[T]) openActive() error {
+	if len(wal.segments) == 0 {
+		return wal.createSegment(1)
+	}
+	segmentFile, err := os.OpenFile(wal.segmentPath(wal.segments[len(wal.segments)-1].id), os.O_WRONLY|os.O_APPEND, 0640)
+	if err != nil {
+		return err
+	}
+	wal.active = segmentFile
+	return nil
+}
+
+// replay restores the queue out of the existing segments in the order the entries were pushed originally
+func (wal *WAL[T]) replay() error {
+	dirEntries, err := os.ReadDir(wal.dir)
+	if err != nil {
+		return err
+	}
+	var ids []uint64
+	for _, dirEntry := range dirEntries {
+		name := dirEntry.Name()
+		if dirEntry.IsDir() || !strings.HasSuffix(name, segmentExtension) {
+			continue
+		}
+		id, err := strconv.ParseUint(strings.TrimSuffix(name, segmentExtension), 10, 64)
+		if err != nil {
+			continue
+		}
+		ids = append(ids, id)
+	}
+	sort.Slice(ids, func(i, j int) bool {
+		return ids[i] < ids[j]
+	})
+
+	entries := make(map[uint64]*Entry[T])
+	for i, id := range ids {
+		size, err := wal.replaySegment(id, i == len(ids)-1, entries)
+		if err != nil {
+			return err
+		}
+		wal.segments = append(wal.segments, &segment{
+			id:   id,
+			size: size,
+		})
+		wal.logBytes += size
+	}
+
+	live := make([]*Entry[T], 0, len(entries))
+	for _, entry := range entries {
+		live = append(live, entry)
+		wal.liveBytes += entry.size
+	}
+	sort.Slice(live, func(i, j int) bool {
+		return live[i].seq < live[j].seq
+	})
+
+	// Only keep entries up to the capacity in memory if the others may be spilled
+	for i, entry := range live {
+		if wal.options.Overflow == OverflowSpill && wal.options.Capacity > 0 && i >= wal.options.Capacity {
+			wal.spilled[entry.seq] = spilledEntry{
+				attempts:  entry.Attempts,
+				lastError: entry.LastError,
+			}
+			continue
+		}
+		wal.load(entry)
+	}
+	return nil
+}
+
+// replaySegment applies the records of a single segment and returns its valid size. A torn frame at the end of the
+// last segment stems from an interrupted write and is truncated.
+func (wal *WAL[T]) replaySegment(id uint64, last bool, entries map[uint64]*Entry[T]) (int64, error) {
+	path := wal.segmentPath(id)
+	segmentFile, err := os.Open(path)
+	if err != nil {
+		return 0, err
+	}
+	defer segmentFile.Close()
+
+	reader := bufio.NewReader(segmentFile)
+	var offset int64
+	for {
+		rec, size, err := readFrame[T](reader)
+		if err != nil {
+			if errors.Is(err, io.EOF) {
+				return offset, nil
+			}
+			if !last {
+				return 0, fmt.Errorf("%w: %s at offset %d: %v", ErrCorruptSegment, path, offset, err)
+			}
+			return offset, os.Truncate(path, offset)
+		}
+		offset += size
+
+		if rec.Seq > 0 {
+			if rec.Seq >= wal.nextSeq {
+				wal.nextSeq = rec.Seq + 1
+			}
+			if _, ok := entries[rec.Seq]; !ok {
+				entries[rec.Seq] = &Entry[T]{
+					Value:     rec.Value,
+					Attempts:  rec.Attempts,
+					LastError: rec.Error,
+					seq:       rec.Seq,
+					size:      size,
+				}
+			}
+		}
+		for _, seq := range rec.Failures {
+			if entry, ok := entries[seq]; ok {
+				entry.Attempts++
+				entry.LastError = rec.Error
+			}
+		}
+		for _, seq := range rec.Acks {
+			delete(entries, seq)
+		}
+	}
+}
+
+// encodeFrame encodes a record into a frame consisting of its length, its CRC-32 checksum and its cbor payload
+func encodeFrame[T any](rec *record[T]) ([]byte, error) {
+	payload, err := cbor.Marshal(rec)
+	if err != nil {
+		return nil, err
+	}
+	frame := make([]byte, frameHeaderSize, frameHeaderSize+len(payload))
+	binary.BigEndian.PutUint32(frame[0:4], uint32(len(payload)))
+	binary.BigEndian.PutUint32(frame[4:8], crc32.ChecksumIEEE(payload))
+	return append(frame, payload...), nil
+}
+
+// readFrame reads and decodes a single frame; io.EOF is only returned if the reader ended right before the frame
+func readFrame[T any](reader io.Reader) (*record[T], int64, error) {
+	header := make([]byte, frameHeaderSize)
+	if _, err := io.ReadFull(reader, header); err != nil {
+		if errors.Is(err, io.ErrUnexpectedEOF) {
+			return nil, 0, errors.New("truncated frame header")
+		}
+		return nil, 0, err
+	}
+
+	length := binary.BigEndian.Uint32(header[0:4])
+	if length > maxFrameSize {
+		return nil, 0, fmt.Errorf("frame length %d exceeds the maximum", length)
+	}
+	payload := make([]byte, length)
+	if _, err := io.ReadFull(reader, payload); err != nil {
+		return nil, 0, errors.New("truncated frame payload")
+	}
+	if crc32.ChecksumIEEE(payload) != binary.BigEndian.Uint32(header[4:8]) {
+		return nil, 0, errors.New("frame checksum mismatch")
+	}
+
+	rec := new(record[T])
+	if err := decMode.Unmarshal(payload, rec); err != nil {
+		return nil, 0, err
+	}
+	return rec, int64(frameHeaderSize + len(payload)), nil
+}
@@ -0,0 +1,521 @@
+package queue
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func openTestWAL(t *testing.T, dir string, options Options) *WAL[string] {
+	t.Helper()
+	wal, err := Open[string](dir, options, nil)
+	if err != nil {
+		t.Fatalf("Open() error = %v", err)
+	}
+	return wal
+}
+
+func pushTest(t *testing.T, wal *WAL[string], values ...string) int {
+	t.Helper()
+	dropped, err := wal.Push(values...)
+	if err != nil {
+		t.Fatalf("Push() error = %v", err)
+	}
+	return dropped
+}
+
+func ackTest(t *testing.T, wal *WAL[string], entries ...*Entry[string]) {
+	t.Helper()
+	if err := wal.Ack(entries...); err != nil {
+		t.Fatalf("Ack() error = %v", err)
+	}
+}
+
+func valuesOf(entries []*Entry[string]) []string {
+	values := make([]string, 0, len(entries))
+	for _, entry := range entries {
+		values = append(values, entry.Value)
+	}
+	return values
+}
+
+// drain pops and acknowledges every queued entry and returns their values in the order they were popped
+func drain(t *testing.T, wal *WAL[string]) []string {
+	t.Helper()
+	var values []string
+	for {
+		entries := wal.PopN(10)
+		if len(entries) == 0 {
+			return values
+		}
+		values = append(values, valuesOf(entries)...)
+		ackTest(t, wal, entries...)
+	}
+}
+
+func assertValues(t *testing.T, got []string, want ...string) {
+	t.Helper()
+	if strings.Join(got, ",") != strings.Join(want, ",") {
+		t.Errorf("got %v, want %v", got, want)
+	}
+}
+
+func TestWALOverflow(t *testing.T) {
+	tests := []struct {
+		name        string
+		overflow    OverflowPolicy
+		wantDropped int
+		want        []string
+	}{
+		{
+			name:        "drop oldest",
+			overflow:    OverflowDropOldest,
+			wantDropped: 2,
+			want:        []string{"3", "4", "5"},
+		},
+		{
+			name:        "drop newest",
+			overflow:    OverflowDropNewest,
+			wantDropped: 2,
+			want:        []string{"1", "2", "3"},
+		},
+		{
+			name:     "spill",
+			overflow: OverflowSpill,
+			want:     []string{"1", "2", "3", "4", "5"},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			dir := t.TempDir()
+			wal := openTestWAL(t, dir, Options{Capacity: 3, Overflow: test.overflow})
+			if dropped := pushTest(t, wal, "1", "2", "3", "4", "5"); dropped != test.wantDropped {
+				t.Errorf("Push() dropped = %d, want %d", dropped, test.wantDropped)
+			}
+			if got := wal.Dropped(); got != uint64(test.wantDropped) {
+				t.Errorf("Dropped() = %d, want %d", got, test.wantDropped)
+			}
+			if got := wal.Size(); got != len(test.want) {
+				t.Errorf("Size() = %d, want %d", got, len(test.want))
+			}
+			assertValues(t, drain(t, wal), test.want...)
+			wal.Close()
+
+			// The outcome has to be persisted as well
+			wal = openTestWAL(t, dir, Options{Capacity: 3, Overflow: test.overflow})
+			defer wal.Close()
+			assertValues(t, drain(t, wal))
+		})
+	}
+}
+
+func TestWALOverflowBlock(t *testing.T) {
+	wal := openTestWAL(t, t.TempDir(), Options{Capacity: 2, Overflow: OverflowBlock})
+	defer wal.Close()
+	pushTest(t, wal, "1", "2")
+
+	done := make(chan error)
+	go func() {
+		_, err := wal.Push("3")
+		done <- err
+	}()
+	select {
+	case err := <-done:
+		t.Fatalf("Push() into a full queue returned %v instead of blocking", err)
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	ackTest(t, wal, wal.PopN(1)...)
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatalf("Push() error = %v", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Push() did not unblock after an entry was acknowledged")
+	}
+	assertValues(t, drain(t, wal), "2", "3")
+}
+
+func TestWALOverflowBlockClose(t *testing.T) {
+	wal := openTestWAL(t, t.TempDir(), Options{Capacity: 1, Overflow: OverflowBlock})
+	pushTest(t, wal, "1")
+
+	done := make(chan error)
+	go func() {
+		_, err := wal.Push("2")
+		done <- err
+	}()
+	time.Sleep(50 * time.Millisecond)
+	wal.Close()
+	select {
+	case err := <-done:
+		if !errors.Is(err, os.ErrClosed) {
+			t.Errorf("Push() error = %v, want %v", err, os.ErrClosed)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Push() did not unblock after the queue was closed")
+	}
+}
+
+// TestWALSpillRestart covers spilled entries being restored after a restart preceded by partial acknowledgements
+func TestWALSpillRestart(t *testing.T) {
+	dir := t.TempDir()
+	options := Options{Capacity: 2, Overflow: OverflowSpill}
+	wal := openTestWAL(t, dir, options)
+	for i := 1; i <= 6; i++ {
+		pushTest(t, wal, strconv.Itoa(i))
+	}
+
+	first := wal.PopN(2)
+	assertValues(t, valuesOf(first), "1", "2")
+	ackTest(t, wal, first[0])
+	second := wal.PopN(2)
+	assertValues(t, valuesOf(second), "3", "4")
+	ackTest(t, wal, second[1])
+	wal.Requeue(second[0], first[1])
+	wal.Close()
+
+	wal = openTestWAL(t, dir, options)
+	defer wal.Close()
+	if got := wal.Size(); got != 4 {
+		t.Errorf("Size() = %d, want 4", got)
+	}
+	assertValues(t, drain(t, wal), "2", "3", "5", "6")
+}
+
+// TestWALSpillCompaction covers spilled entries surviving the compaction of the log
+func TestWALSpillCompaction(t *testing.T) {
+	dir := t.TempDir()
+	options := Options{SegmentSize: 256, Capacity: 4, Overflow: OverflowSpill}
+	wal := openTestWAL(t, dir, options)
+	var want []string
+	for i := 0; i < 40; i++ {
+		value := "value-" + strconv.Itoa(i)
+		pushTest(t, wal, value)
+		want = append(want, value)
+	}
+	got := drain(t, wal)
+	wal.Close()
+	assertValues(t, got, want...)
+
+	wal = openTestWAL(t, dir, options)
+	defer wal.Close()
+	assertValues(t, drain(t, wal))
+}
+
+func segmentFiles(t *testing.T, dir string) []string {
+	t.Helper()
+	files, err := filepath.Glob(filepath.Join(dir, "*"+segmentExtension))
+	if err != nil {
+		t.Fatal(err)
+	}
+	sort.Strings(files)
+	return files
+}
+
+func TestWALReplay(t *testing.T) {
+	dir := t.TempDir()
+	wal := openTestWAL(t, dir, Options{})
+	pushTest(t, wal, "1", "2", "3", "4")
+	entries := wal.PopN(2)
+	ackTest(t, wal, entries[0])
+
+	// Reopen the log without closing it to simulate a crash, which only releases the lock of the directory; the popped
+	// but unacknowledged entry is restored
+	wal.lock.Close()
+	crashed := openTestWAL(t, dir, Options{})
+	defer crashed.Close()
+	wal.Close()
+	if got := crashed.Size(); got != 3 {
+		t.Errorf("Size() = %d, want 3", got)
+	}
+	assertValues(t, drain(t, crashed), "2", "3", "4")
+}
+
+func TestWALLocked(t *testing.T) {
+	dir := t.TempDir()
+	wal := openTestWAL(t, dir, Options{})
+	if other, err := Open[string](dir, Options{}, nil); !errors.Is(err, ErrLocked) {
+		if other != nil {
+			other.Close()
+		}
+		t.Fatalf("Open() of a locked queue error = %v, want %v", err, ErrLocked)
+	}
+	wal.Close()
+
+	wal = openTestWAL(t, dir, Options{})
+	wal.Close()
+}
+
+func TestWALFailures(t *testing.T) {
+	dir := t.TempDir()
+	wal := openTestWAL(t, dir, Options{})
+	pushTest(t, wal, "1", "2")
+	entries := wal.PopN(2)
+	for _, reason := range []string{"first", "second"} {
+		if err := wal.Fail(reason, entries[0]); err != nil {
+			t.Fatalf("Fail() error = %v", err)
+		}
+	}
+	wal.Requeue(entries...)
+	if entries[0].Attempts != 2 || entries[0].LastError != "second" {
+		t.Errorf("entry has %d attempts and last error %q, want 2 and %q", entries[0].Attempts, entries[0].LastError, "second")
+	}
+	wal.Close()
+
+	wal = openTestWAL(t, dir, Options{})
+	defer wal.Close()
+	entries = wal.PopN(2)
+	assertValues(t, valuesOf(entries), "1", "2")
+	if entries[0].Attempts != 2 || entries[0].LastError != "second" {
+		t.Errorf("restored entry has %d attempts and last error %q, want 2 and %q", entries[0].Attempts, entries[0].LastError, "second")
+	}
+	if entries[1].Attempts != 0 {
+		t.Errorf("restored entry has %d attempts, want 0", entries[1].Attempts)
+	}
+}
+
+func TestWALPushEntries(t *testing.T) {
+	dir := t.TempDir()
+	wal := openTestWAL(t, dir, Options{Capacity: 1, Overflow: OverflowSpill})
+	_, err := wal.PushEntries(
+		&Entry[string]{Value: "1", Attempts: 2, LastError: "rejected"},
+		&Entry[string]{Value: "2", Attempts: 1, LastError: "spilled"},
+	)
+	if err != nil {
+		t.Fatalf("PushEntries() error = %v", err)
+	}
+	wal.Close()
+
+	// Both the loaded and the spilled entry keep their attempts across restarts
+	wal = openTestWAL(t, dir, Options{Capacity: 1, Overflow: OverflowSpill})
+	defer wal.Close()
+	var restored []*Entry[string]
+	for wal.Size() > 0 {
+		entries := wal.PopN(1)
+		restored = append(restored, entries...)
+		ackTest(t, wal, entries...)
+	}
+	assertValues(t, valuesOf(restored), "1", "2")
+	if restored[0].Attempts != 2 || restored[0].LastError != "rejected" || restored[1].Attempts != 1 || restored[1].LastError != "spilled" {
+		t.Errorf("restored entries %+v, want their attempts and last errors", restored)
+	}
+}
+
+func TestWALCompaction(t *testing.T) {
+	dir := t.TempDir()
+	options := Options{SegmentSize: 256}
+	wal := openTestWAL(t, dir, options)
+	for i := 0; i < 50; i++ {
+		pushTest(t, wal, "value-"+strconv.Itoa(i))
+	}
+	failed := wal.PopN(1)
+	if err := wal.Fail("rejected", failed...); err != nil {
+		t.Fatalf("Fail() error = %v", err)
+	}
+	wal.Requeue(failed...)
+	if len(segmentFiles(t, dir)) < 2 {
+		t.Fatal("log did not roll over into several segments")
+	}
+
+	// Keep the last two entries and the requeued one; acknowledging the others compacts the log
+	ackTest(t, wal, wal.PopN(47)...)
+	if got := len(segmentFiles(t, dir)); got != 1 {
+		t.Errorf("log consists of %d segments after compaction, want 1", got)
+	}
+	wal.Close()
+
+	wal = openTestWAL(t, dir, options)
+	defer wal.Close()
+	entries := wal.PopN(10)
+	assertValues(t, valuesOf(entries), "value-0", "value-48", "value-49")
+	if len(entries) > 0 && (entries[0].Attempts != 1 || entries[0].LastError != "rejected") {
+		t.Errorf("compacted entry has %d attempts and last error %q, want 1 and %q", entries[0].Attempts, entries[0].LastError, "rejected")
+	}
+}
+
+// TestWALInterruptedCompaction covers a crash while the old segments were removed after compacting the log
+func TestWALInterruptedCompaction(t *testing.T) {
+	dir := t.TempDir()
+	wal := openTestWAL(t, dir, Options{SegmentSize: 128})
+	for i := 0; i < 50; i++ {
+		pushTest(t, wal, "value-"+strconv.Itoa(i))
+	}
+
+	// Acknowledge entries without compacting the log and remember the final state of its segments
+	wal.options.SegmentSize = 1 << 30
+	ackTest(t, wal, wal.PopN(47)...)
+	old := segmentFiles(t, dir)
+	if len(old) < 3 {
+		t.Fatal("log did not roll over into several segments")
+	}
+	contents := make(map[string][]byte, len(old))
+	for _, path := range old {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		contents[path] = data
+	}
+
+	wal.Lock()
+	err := wal.compact()
+	wal.Unlock()
+	if err != nil {
+		t.Fatalf("compact() error = %v", err)
+	}
+	wal.Close()
+
+	// Restore every old segment but the oldest one as if the removal was interrupted
+	for _, path := range old[1:] {
+		if err := os.WriteFile(path, contents[path], 0640); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	wal = openTestWAL(t, dir, Options{SegmentSize: 128})
+	defer wal.Close()
+	assertValues(t, drain(t, wal), "value-47", "value-48", "value-49")
+}
+
+func TestWALTruncatedFrame(t *testing.T) {
+	dir := t.TempDir()
+	wal := openTestWAL(t, dir, Options{})
+	pushTest(t, wal, "1", "2")
+	wal.Close()
+
+	// Append a frame header announcing more payload than was written, like an interrupted write does
+	files := segmentFiles(t, dir)
+	last := files[len(files)-1]
+	stat, err := os.Stat(last)
+	if err != nil {
+		t.Fatal(err)
+	}
+	segmentFile, err := os.OpenFile(last, os.O_WRONLY|os.O_APPEND, 0640)
+	if err != nil {
+		t.Fatal(err)
+	}
+	segmentFile.Write([]byte{0, 0, 0, 100, 1, 2, 3, 4, 5, 6})
+	segmentFile.Close()
+
+	wal = openTestWAL(t, dir, Options{})
+	if truncated, err := os.Stat(last); err != nil || truncated.Size() != stat.Size() {
+		t.Errorf("torn frame was not truncated")
+	}
+	pushTest(t, wal, "3")
+	wal.Close()
+
+	wal = openTestWAL(t, dir, Options{})
+	defer wal.Close()
+	assertValues(t, drain(t, wal), "1", "2", "3")
+}
+
+func TestWALCorruptSegment(t *testing.T) {
+	dir := t.TempDir()
+	wal := openTestWAL(t, dir, Options{SegmentSize: 64})
+	for i := 0; i < 10; i++ {
+		pushTest(t, wal, "value-"+strconv.Itoa(i))
+	}
+	wal.Close()
+
+	// Corrupt a frame inside a segment that is not the last one
+	first := segmentFiles(t, dir)[0]
+	data, err := os.ReadFile(first)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data[len(data)-1] ^= 0xff
+	if err := os.WriteFile(first, data, 0640); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := Open[string](dir, Options{SegmentSize: 64}, nil); !errors.Is(err, ErrCorruptSegment) {
+		t.Errorf("Open() error = %v, want %v", err, ErrCorruptSegment)
+	}
+}
+
+func numericPriority(value string) int64 {
+	priority, _ := strconv.ParseInt(value, 10, 64)
+	return priority
+}
+
+func TestWALPriorityOrder(t *testing.T) {
+	dir := t.TempDir()
+	options := Options{Order: OrderPriority}
+	wal, err := Open[string](dir, options, numericPriority)
+	if err != nil {
+		t.Fatalf("Open() error = %v", err)
+	}
+	pushTest(t, wal, "3", "1", "5", "2", "4")
+
+	entries := wal.PopN(2)
+	assertValues(t, valuesOf(entries), "5", "4")
+	wal.Requeue(entries[1])
+	wal.Close()
+
+	// The order has to be restored from the log as well
+	wal, err = Open[string](dir, options, numericPriority)
+	if err != nil {
+		t.Fatalf("Open() error = %v", err)
+	}
+	defer wal.Close()
+	assertValues(t, drain(t, wal), "5", "4", "3", "2", "1")
+}
+
+func TestWALPriorityBacklogShare(t *testing.T) {
+	tests := []struct {
+		name  string
+		share float64
+		n     int
+		want  [][]string
+	}{
+		{
+			name: "no backlog",
+			n:    3,
+			want: [][]string{{"8", "7", "6"}, {"5", "4", "3"}, {"2", "1"}},
+		},
+		{
+			name:  "rounded up",
+			share: 0.2,
+			n:     3,
+			want:  [][]string{{"1", "8", "7"}, {"2", "6", "5"}, {"3", "4"}},
+		},
+		{
+			name:  "half",
+			share: 0.5,
+			n:     4,
+			want:  [][]string{{"1", "2", "8", "7"}, {"3", "4", "6", "5"}},
+		},
+		{
+			name:  "only backlog",
+			share: 1,
+			n:     3,
+			want:  [][]string{{"1", "2", "3"}, {"4", "5", "6"}, {"7", "8"}},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			wal, err := Open[string](t.TempDir(), Options{Order: OrderPriority, BacklogShare: test.share}, numericPriority)
+			if err != nil {
+				t.Fatalf("Open() error = %v", err)
+			}
+			defer wal.Close()
+			pushTest(t, wal, "1", "2", "3", "4", "5", "6", "7", "8")
+
+			for _, want := range test.want {
+				entries := wal.PopN(test.n)
+				assertValues(t, valuesOf(entries), want...)
+				ackTest(t, wal, entries...)
+			}
+			if got := wal.Size(); got != 0 {
+				t.Errorf("Size() = %d, want 0", got)
+			}
+		})
+	}
+}
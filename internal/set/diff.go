@@ -25,3 +25,24 @@ func Diff[T comparable](first, second *HashSet[T]) *HashSet[T] {
 		underlying: diff,
 	}
 }
+
+// Added returns a set containing every element of current that does not exist in previous
+func Added[T comparable](current, previous *HashSet[T]) *HashSet[T] {
+	current.RLock()
+	defer current.RUnlock()
+	if previous != current {
+		previous.RLock()
+		defer previous.RUnlock()
+	}
+
+	added := make(map[T]struct{})
+	for value := range current.underlying {
+		if _, ok := previous.underlying[value]; !ok {
+			added[value] = struct{}{}
+		}
+	}
+
+	return &HashSet[T]{
+		underlying: added,
+	}
+}
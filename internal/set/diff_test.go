@@ -0,0 +1,104 @@
+package set
+
+import (
+	"sort"
+	"testing"
+)
+
+func newSet(values ...string) *HashSet[string] {
+	set := NewHashSet[string]()
+	for _, value := range values {
+		set.Add(value)
+	}
+	return set
+}
+
+func sorted(set *HashSet[string]) []string {
+	values := set.ToSlice()
+	sort.Strings(values)
+	return values
+}
+
+func equal(first, second []string) bool {
+	if len(first) != len(second) {
+		return false
+	}
+	for i := range first {
+		if first[i] != second[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func TestDiff(t *testing.T) {
+	diff := Diff(newSet("a", "b", "c"), newSet("b", "c", "d"))
+	if got, want := sorted(diff), []string{"a", "d"}; !equal(got, want) {
+		t.Errorf("Diff() = %v, want %v", got, want)
+	}
+}
+
+func TestAdded(t *testing.T) {
+	tests := []struct {
+		name     string
+		current  []string
+		previous []string
+		want     []string
+	}{
+		{
+			name:    "empty previous",
+			current: []string{"a", "b"},
+			want:    []string{"a", "b"},
+		},
+		{
+			name:     "unchanged",
+			current:  []string{"a", "b"},
+			previous: []string{"a", "b"},
+			want:     []string{},
+		},
+		{
+			name:     "appended",
+			current:  []string{"a", "b", "c"},
+			previous: []string{"a", "b"},
+			want:     []string{"c"},
+		},
+		{
+			name:     "removed",
+			current:  []string{"b"},
+			previous: []string{"a", "b"},
+			want:     []string{},
+		},
+		{
+			name:     "rotated",
+			current:  []string{"c", "d"},
+			previous: []string{"a", "b"},
+			want:     []string{"c", "d"},
+		},
+		{
+			name:     "partially rotated",
+			current:  []string{"b", "c"},
+			previous: []string{"a", "b"},
+			want:     []string{"c"},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			current := newSet(test.current...)
+			previous := newSet(test.previous...)
+			if got := sorted(Added(current, previous)); !equal(got, test.want) {
+				t.Errorf("Added() = %v, want %v", got, test.want)
+			}
+			if current.Size() != len(test.current) || previous.Size() != len(test.previous) {
+				t.Error("Added() modified its inputs")
+			}
+		})
+	}
+}
+
+func TestAddedSameSet(t *testing.T) {
+	set := newSet("a", "b")
+	if added := Added(set, set); added.Size() != 0 {
+		t.Errorf("Added() = %v, want []", sorted(added))
+	}
+}
@@ -33,6 +33,13 @@ func (set *HashSet[T]) Add(value T) {
 	set.underlying[value] = struct{}{}
 }
 
+// Remove removes a value from the set
+func (set *HashSet[T]) Remove(value T) {
+	set.Lock()
+	defer set.Unlock()
+	delete(set.underlying, value)
+}
+
 // ToSlice returns a slice containing every element of the set
 func (set *HashSet[T]) ToSlice() []T {
 	set.Lock()
@@ -0,0 +1,59 @@
+package sink
+
+import (
+	"errors"
+	"fmt"
+	"github.com/skybi/nuntius/internal/client"
+	"net/http"
+)
+
+// API represents a sink feeding reports into the data API
+type API struct {
+	name string
+	feed func(reports []string) error
+}
+
+var _ Sink = (*API)(nil)
+
+// NewMETARAPI creates a new sink feeding METARs into the data API using the given client
+func NewMETARAPI(apiClient *client.Client) *API {
+	return &API{
+		name: "api",
+		feed: apiClient.FeedMETARsRelaxed,
+	}
+}
+
+// NewTAFAPI creates a new sink feeding TAFs into the data API using the given client
+func NewTAFAPI(apiClient *client.Client) *API {
+	return &API{
+		name: "api",
+		feed: apiClient.FeedTAFsRelaxed,
+	}
+}
+
+// Name returns the name of the sink
+func (sink *API) Name() string {
+	return sink.name
+}
+
+// Feed feeds reports into the data API, skipping the ones with an invalid format.
+// Errors caused by the reports themselves rather than the availability of the data API wrap ErrRejected.
+func (sink *API) Feed(reports []string) error {
+	err := sink.feed(reports)
+	var errResponse *client.APIErrorResponse
+	if errors.As(err, &errResponse) && rejected(errResponse.Status) {
+		return fmt.Errorf("%w: %v", ErrRejected, err)
+	}
+	return err
+}
+
+// rejected reports whether a response status indicates that the data API refused the fed reports themselves; every
+// other error status (e.g. server failures or a wrong address) is not caused by the reports and may resolve itself
+func rejected(status int) bool {
+	switch status {
+	case http.StatusBadRequest, http.StatusRequestEntityTooLarge, http.StatusUnprocessableEntity:
+		return true
+	default:
+		return false
+	}
+}
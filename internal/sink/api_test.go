@@ -0,0 +1,34 @@
+package sink
+
+import (
+	"errors"
+	"github.com/skybi/nuntius/internal/client"
+	"testing"
+)
+
+func TestAPIFeedRejected(t *testing.T) {
+	tests := []struct {
+		status int
+		want   bool
+	}{
+		{status: 400, want: true},
+		{status: 413, want: true},
+		{status: 422, want: true},
+		{status: 401},
+		{status: 404},
+		{status: 429},
+		{status: 500},
+		{status: 503},
+	}
+	for _, test := range tests {
+		sink := &API{
+			name: "api",
+			feed: func(_ []string) error {
+				return &client.APIErrorResponse{Status: test.status}
+			},
+		}
+		if got := errors.Is(sink.Feed([]string{"EDDF"}), ErrRejected); got != test.want {
+			t.Errorf("status %d: rejected = %v, want %v", test.status, got, test.want)
+		}
+	}
+}
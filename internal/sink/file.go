@@ -0,0 +1,56 @@
+package sink
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+// File represents a sink appending reports line by line to a local file
+type File struct {
+	sync.Mutex
+	path string
+}
+
+var _ Sink = (*File)(nil)
+
+// NewFile creates a new sink appending reports to the file at path
+func NewFile(path string) *File {
+	return &File{
+		path: path,
+	}
+}
+
+// Name returns the name of the sink
+func (sink *File) Name() string {
+	return "file"
+}
+
+// Feed appends reports to the file, creating it if it does not exist
+func (sink *File) Feed(reports []string) error {
+	if len(reports) == 0 {
+		return nil
+	}
+
+	sink.Lock()
+	defer sink.Unlock()
+
+	abs, err := filepath.Abs(sink.path)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(abs), 0750); err != nil && !errors.Is(err, os.ErrExist) {
+		return err
+	}
+
+	file, err := os.OpenFile(abs, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0640)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	_, err = file.WriteString(strings.Join(reports, "\n") + "\n")
+	return err
+}
@@ -0,0 +1,34 @@
+package sink
+
+import "fmt"
+
+// Multi represents a sink feeding reports into several other sinks
+type Multi struct {
+	sinks []Sink
+}
+
+var _ Sink = (*Multi)(nil)
+
+// NewMulti creates a new sink feeding reports into all the given sinks
+func NewMulti(sinks ...Sink) *Multi {
+	return &Multi{
+		sinks: sinks,
+	}
+}
+
+// Name returns the name of the sink
+func (sink *Multi) Name() string {
+	return "multi"
+}
+
+// Feed feeds reports into every underlying sink, each of them receiving its own copy of the batch.
+// An error is returned as soon as one of the sinks fails, meaning that retrying the batch may feed it into the
+// preceding sinks again.
+func (sink *Multi) Feed(reports []string) error {
+	for _, underlying := range sink.sinks {
+		if err := underlying.Feed(append([]string(nil), reports...)); err != nil {
+			return fmt.Errorf("sink '%s': %w", underlying.Name(), err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,41 @@
+package sink
+
+import (
+	"strings"
+	"testing"
+)
+
+// recordingSink records the batches it receives and overwrites them afterwards like a sink dropping rejected reports
+// in place would
+type recordingSink struct {
+	name string
+	fed  []string
+}
+
+func (recording *recordingSink) Name() string {
+	return recording.name
+}
+
+func (recording *recordingSink) Feed(reports []string) error {
+	recording.fed = append(recording.fed, reports...)
+	for i := range reports {
+		reports[i] = "MODIFIED"
+	}
+	return nil
+}
+
+func TestMultiFeedCopies(t *testing.T) {
+	first, second := &recordingSink{name: "first"}, &recordingSink{name: "second"}
+	reports := []string{"EDDF 1", "EDDM 2", "EDDH 3"}
+	if err := NewMulti(first, second).Feed(reports); err != nil {
+		t.Fatalf("Feed() error = %v", err)
+	}
+	for _, recording := range []*recordingSink{first, second} {
+		if got := strings.Join(recording.fed, "|"); got != "EDDF 1|EDDM 2|EDDH 3" {
+			t.Errorf("sink %s received %q", recording.name, recording.fed)
+		}
+	}
+	if got := strings.Join(reports, "|"); got != "EDDF 1|EDDM 2|EDDH 3" {
+		t.Errorf("Feed() modified the batch into %q", reports)
+	}
+}
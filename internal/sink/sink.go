@@ -0,0 +1,16 @@
+package sink
+
+import "errors"
+
+// ErrRejected is wrapped by the errors of sinks that received reports but refused to accept them, meaning that
+// feeding the same reports again is not expected to succeed
+var ErrRejected = errors.New("reports were rejected")
+
+// Sink represents a destination the feeder drains reports into
+type Sink interface {
+	// Name returns the unique name of the sink used for logging
+	Name() string
+
+	// Feed feeds a batch of reports into the destination
+	Feed(reports []string) error
+}
@@ -0,0 +1,116 @@
+package awc
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"encoding/csv"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// Format represents the format of a cache file
+type Format string
+
+const (
+	FormatCSV Format = "csv"
+	FormatXML Format = "xml"
+)
+
+// ParseFormat parses the name of a cache file format
+func ParseFormat(raw string) (Format, error) {
+	format := Format(strings.ToLower(strings.TrimSpace(raw)))
+	switch format {
+	case FormatCSV, FormatXML:
+		return format, nil
+	default:
+		return "", fmt.Errorf("unknown cache file format '%s'", raw)
+	}
+}
+
+// DefaultURL returns the URL of the METAR cache file of the given format published by AviationWeather.gov
+func DefaultURL(format Format) string {
+	return "https://aviationweather.gov/data/cache/metars.cache." + string(format) + ".gz"
+}
+
+// decompress transparently decompresses gzipped data
+func decompress(data []byte) (io.Reader, error) {
+	if len(data) < 2 || data[0] != 0x1f || data[1] != 0x8b {
+		return bytes.NewReader(data), nil
+	}
+	return gzip.NewReader(bytes.NewReader(data))
+}
+
+// extractCSV extracts the raw_text column out of a CSV cache file.
+// The actual header is preceded by several lines of metadata (errors, warnings, timing, result count).
+func extractCSV(reader io.Reader) ([]string, error) {
+	csvReader := csv.NewReader(bufio.NewReader(reader))
+	csvReader.FieldsPerRecord = -1
+	csvReader.ReuseRecord = true
+
+	column := -1
+	var raws []string
+	for {
+		record, err := csvReader.Read()
+		if err != nil {
+			if errors.Is(err, io.EOF) {
+				break
+			}
+			return nil, err
+		}
+
+		if column < 0 {
+			for i, field := range record {
+				if field == "raw_text" {
+					column = i
+					break
+				}
+			}
+			continue
+		}
+
+		if column < len(record) && record[column] != "" {
+			raws = append(raws, record[column])
+		}
+	}
+
+	if column < 0 {
+		return nil, errors.New("cache file does not contain a raw_text column")
+	}
+	return raws, nil
+}
+
+// extractXML extracts the raw_text elements of all METAR elements out of an XML cache file
+func extractXML(reader io.Reader) ([]string, error) {
+	decoder := xml.NewDecoder(bufio.NewReader(reader))
+
+	var raws []string
+	for {
+		token, err := decoder.Token()
+		if err != nil {
+			if errors.Is(err, io.EOF) {
+				break
+			}
+			return nil, err
+		}
+
+		start, ok := token.(xml.StartElement)
+		if !ok || start.Name.Local != "METAR" {
+			continue
+		}
+
+		metar := new(struct {
+			RawText string `xml:"raw_text"`
+		})
+		if err := decoder.DecodeElement(metar, &start); err != nil {
+			return nil, err
+		}
+		if raw := strings.TrimSpace(metar.RawText); raw != "" {
+			raws = append(raws, raw)
+		}
+	}
+	return raws, nil
+}
@@ -0,0 +1,148 @@
+package awc
+
+import (
+	"fmt"
+	"github.com/rs/zerolog/log"
+	"github.com/skybi/nuntius/internal/set"
+	"github.com/skybi/nuntius/internal/source"
+	"io"
+	"net/http"
+	"time"
+)
+
+// Source represents a source periodically downloading one of the METAR cache files published by AviationWeather.gov
+type Source struct {
+	url           string
+	format        Format
+	client        *http.Client
+	stateFilePath string
+
+	etag         string
+	lastModified string
+
+	emit   source.EmitFunc
+	poller *source.Poller
+}
+
+var _ source.Source = (*Source)(nil)
+
+// New creates a new cache file source downloading the file at url every interval and persisting its state into
+// stateFilePath
+func New(url string, format Format, client *http.Client, stateFilePath string, interval time.Duration) *Source {
+	src := &Source{
+		url:           url,
+		format:        format,
+		client:        client,
+		stateFilePath: stateFilePath,
+	}
+	src.poller = source.NewPoller(interval, src.poll)
+	return src
+}
+
+// Name returns the name of the source
+func (src *Source) Name() string {
+	return "awc-cache"
+}
+
+// Start starts polling the cache file
+func (src *Source) Start(emit source.EmitFunc) error {
+	src.emit = emit
+	src.poller.Start()
+	return nil
+}
+
+// Stop stops polling the cache file
+func (src *Source) Stop() {
+	src.poller.Stop()
+}
+
+func (src *Source) poll() {
+	// Download the cache file if it changed since we processed it the previous time
+	data, etag, lastModified, changed, err := src.download()
+	if err != nil {
+		log.Error().Err(err).Str("url", src.url).Msg("could not download cache file")
+		return
+	}
+	if !changed {
+		return
+	}
+
+	// Extract the raw METARs out of the file
+	reader, err := decompress(data)
+	if err != nil {
+		log.Error().Err(err).Msg("could not decompress cache file")
+		return
+	}
+	var raws []string
+	switch src.format {
+	case FormatXML:
+		raws, err = extractXML(reader)
+	default:
+		raws, err = extractCSV(reader)
+	}
+	if err != nil {
+		log.Error().Err(err).Msg("could not extract METARs out of cache file")
+		return
+	}
+	metars := set.NewHashSet[string]()
+	for _, raw := range raws {
+		metars.Add(raw)
+	}
+
+	// Load the METARs we processed the previous time
+	state, err := source.LoadState(src.stateFilePath)
+	if err != nil {
+		log.Error().Err(err).Msg("could not read current cache state")
+		return
+	}
+
+	// Emit the METARs that were not present the previous time
+	values := set.Added(metars, state).ToSlice()
+	if err := src.emit(source.NewReports(src.Name(), src.url, values)); err != nil {
+		log.Error().Err(err).Str("source", src.Name()).Msg("could not emit reports")
+		return
+	}
+	log.Debug().Str("source", src.Name()).Int("amount", len(values)).Msg("emitted reports")
+
+	// Update the state; the cache file only contains the latest METARs, so the old state is replaced entirely
+	if err := source.SaveState(src.stateFilePath, metars); err != nil {
+		log.Error().Err(err).Msg("could not update current cache state")
+		return
+	}
+
+	src.etag = etag
+	src.lastModified = lastModified
+}
+
+// download downloads the cache file if it changed since it was processed the previous time
+func (src *Source) download() ([]byte, string, string, bool, error) {
+	request, err := http.NewRequest(http.MethodGet, src.url, nil)
+	if err != nil {
+		return nil, "", "", false, err
+	}
+	if src.etag != "" {
+		request.Header.Set("If-None-Match", src.etag)
+	}
+	if src.lastModified != "" {
+		request.Header.Set("If-Modified-Since", src.lastModified)
+	}
+
+	response, err := src.client.Do(request)
+	if err != nil {
+		return nil, "", "", false, err
+	}
+	defer response.Body.Close()
+
+	switch response.StatusCode {
+	case http.StatusOK:
+		data, err := io.ReadAll(response.Body)
+		if err != nil {
+			return nil, "", "", false, err
+		}
+		return data, response.Header.Get("ETag"), response.Header.Get("Last-Modified"), true, nil
+	case http.StatusNotModified:
+		return nil, "", "", false, nil
+	default:
+		return nil, "", "", false, fmt.Errorf("HTTP status %d", response.StatusCode)
+	}
+}
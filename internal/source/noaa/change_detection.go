@@ -0,0 +1,93 @@
+package noaa
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"github.com/jlaffaye/ftp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ChangeDetection represents a strategy used to detect whether a cycle file changed since the previous poll
+type ChangeDetection string
+
+const (
+	// ChangeDetectionAuto uses ChangeDetectionMDTM if the server supports it and ChangeDetectionSize otherwise
+	ChangeDetectionAuto ChangeDetection = "auto"
+
+	// ChangeDetectionMDTM compares the modification time reported by the MDTM command
+	ChangeDetectionMDTM ChangeDetection = "mdtm"
+
+	// ChangeDetectionSize compares the file size reported by the SIZE command
+	ChangeDetectionSize ChangeDetection = "size"
+
+	// ChangeDetectionList compares the modification time and size contained in the directory listing
+	ChangeDetectionList ChangeDetection = "list"
+
+	// ChangeDetectionHash compares the hash of the downloaded contents and skips processing them if it did not change
+	ChangeDetectionHash ChangeDetection = "hash"
+)
+
+// ParseChangeDetection parses the name of a change detection strategy
+func ParseChangeDetection(raw string) (ChangeDetection, error) {
+	detection := ChangeDetection(strings.ToLower(strings.TrimSpace(raw)))
+	switch detection {
+	case ChangeDetectionAuto, ChangeDetectionMDTM, ChangeDetectionSize, ChangeDetectionList, ChangeDetectionHash:
+		return detection, nil
+	default:
+		return "", fmt.Errorf("unknown change detection strategy '%s'", raw)
+	}
+}
+
+// remoteFingerprint retrieves a value that changes whenever the remote file changes without downloading it.
+// An empty fingerprint means that the strategy can not tell and the file has to be downloaded.
+func remoteFingerprint(detection ChangeDetection, ftpConn *ftp.ServerConn, path string) (string, error) {
+	if detection == ChangeDetectionAuto {
+		if ftpConn.IsGetTimeSupported() {
+			detection = ChangeDetectionMDTM
+		} else {
+			detection = ChangeDetectionSize
+		}
+	}
+
+	switch detection {
+	case ChangeDetectionMDTM:
+		if !ftpConn.IsGetTimeSupported() {
+			return "", nil
+		}
+		changed, err := ftpConn.GetTime(path)
+		if err != nil {
+			return "", err
+		}
+		return changed.Format(time.RFC3339), nil
+	case ChangeDetectionSize:
+		size, err := ftpConn.FileSize(path)
+		if err != nil {
+			return "", err
+		}
+		return strconv.FormatInt(size, 10), nil
+	case ChangeDetectionList:
+		entries, err := ftpConn.List(path)
+		if err != nil {
+			return "", err
+		}
+		if len(entries) != 1 {
+			return "", errors.New("remote file not found in directory listing")
+		}
+		return fmt.Sprintf("%s/%d", entries[0].Time.Format(time.RFC3339), entries[0].Size), nil
+	default:
+		return "", nil
+	}
+}
+
+// contentFingerprint calculates a value that changes whenever the downloaded contents change
+func contentFingerprint(detection ChangeDetection, start int64, data []byte) string {
+	if detection != ChangeDetectionHash {
+		return ""
+	}
+	hash := sha256.Sum256(data)
+	return strconv.FormatInt(start, 10) + "/" + hex.EncodeToString(hash[:])
+}
@@ -0,0 +1,110 @@
+package noaa
+
+import (
+	"fmt"
+	"github.com/skybi/nuntius/internal/source"
+	"path"
+	"path/filepath"
+)
+
+const (
+	// METARCyclesLocation is the location of the hourly METAR cycle files relative to the data server's base path
+	METARCyclesLocation = "observations/metar/cycles/"
+
+	// TAFCyclesLocation is the location of the hourly TAF cycle files relative to the data server's base path
+	TAFCyclesLocation = "forecasts/taf/cycles/"
+)
+
+// Format describes how the reports are laid out inside the cycle files
+type Format struct {
+	Extract source.ExtractFunc
+
+	// Normalize normalizes a raw report before it gets deduplicated so that reports only differing in their formatting
+	// are treated as equal; it may be nil
+	Normalize func(raw string) string
+
+	// Separator separates two records of a cycle file; files are only consumed up to the end of their last separator
+	// to never split a report that is still being written
+	Separator []byte
+}
+
+// CycleSourceConfig represents the configuration of a single cycle source
+type CycleSourceConfig struct {
+	Name string
+
+	// Location is the directory containing the cycle files relative to the base path of the transport
+	Location string
+
+	// FilePattern is the name pattern of the cycle files which is formatted using the cycle hour
+	FilePattern string
+
+	// StateDir is the local directory the cycle states are persisted into
+	StateDir string
+
+	Format   Format
+	Schedule Schedule
+}
+
+// CycleSource represents a source fetching the 24 hourly cycle files of a directory on the NOAA's data server
+type CycleSource struct {
+	name      string
+	transport Transport
+	format    Format
+	schedule  Schedule
+
+	workers [24]*cycleWorker
+	running bool
+}
+
+var _ source.Source = (*CycleSource)(nil)
+
+// NewCycleSource creates a new cycle source fetching its cycle files using the given transport
+func NewCycleSource(transport Transport, config CycleSourceConfig) *CycleSource {
+	src := &CycleSource{
+		name:      config.Name,
+		transport: transport,
+		format:    config.Format,
+		schedule:  config.Schedule,
+	}
+
+	// Create the 24 workers
+	for i := 0; i < 24; i++ {
+		statePath, _ := filepath.Abs(filepath.Join(config.StateDir, fmt.Sprintf("cycle-state-%02d", i)))
+		src.workers[i] = &cycleWorker{
+			src:           src,
+			cycle:         i,
+			file:          transport.file(path.Join(config.Location, fmt.Sprintf(config.FilePattern, i))),
+			stateFilePath: statePath,
+		}
+	}
+
+	return src
+}
+
+// Name returns the name of the source
+func (src *CycleSource) Name() string {
+	return src.name
+}
+
+// Start starts all cycle workers
+func (src *CycleSource) Start(emit source.EmitFunc) error {
+	if src.running {
+		return nil
+	}
+	for _, worker := range src.workers {
+		worker.start(emit)
+	}
+	src.running = true
+	return nil
+}
+
+// Stop stops all cycle workers
+func (src *CycleSource) Stop() {
+	if !src.running {
+		return
+	}
+	for _, worker := range src.workers {
+		worker.stop()
+	}
+	src.running = false
+}
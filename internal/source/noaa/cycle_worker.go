@@ -0,0 +1,161 @@
+package noaa
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"github.com/rs/zerolog/log"
+	"github.com/skybi/nuntius/internal/set"
+	"github.com/skybi/nuntius/internal/source"
+	"time"
+)
+
+// tailLength is the amount of already consumed bytes that are downloaded again when resuming a file to detect if it
+// was rotated in the meantime
+const tailLength = 64
+
+// cycleWorker represents a worker fetching, deduplicating and emitting a single cycle file of the NOAA's data server
+type cycleWorker struct {
+	src   *CycleSource
+	cycle int
+	file  remoteFile
+
+	// offset is the amount of bytes of the remote file that were already consumed and tail contains the last of them
+	offset int64
+	tail   []byte
+
+	stateFilePath string
+	emit          source.EmitFunc
+	poller        *source.Poller
+}
+
+func (worker *cycleWorker) start(emit source.EmitFunc) {
+	if worker.poller != nil {
+		return
+	}
+
+	// A state that could not be migrated only causes its reports to be emitted again, which the feeders deduplicate,
+	// so it must not keep the worker from polling
+	if err := worker.migrateState(); err != nil {
+		log.Warn().Err(err).Str("source", worker.src.name).Int("cycle", worker.cycle).Msg("could not migrate cycle state")
+	}
+
+	worker.emit = emit
+	worker.poller = source.NewScheduledPoller(func() time.Duration {
+		return worker.src.schedule.next(worker.cycle, time.Now())
+	}, worker.poll)
+	worker.poller.Start()
+}
+
+func (worker *cycleWorker) poll() {
+	// Download the part of the file we did not consume yet if it changed since we processed it the previous time
+	data, start, unchanged, err := worker.file.fetch(worker.offset, worker.tail)
+	if err != nil {
+		if errors.Is(err, ErrBackingOff) {
+			log.Debug().Err(err).Str("file", worker.file.path()).Msg("skipping poll")
+			return
+		}
+		log.Error().Err(err).Str("file", worker.file.path()).Msg("could not read remote file")
+		return
+	}
+	if unchanged {
+		return
+	}
+	incremental := start > 0
+	if !incremental {
+		worker.offset = 0
+		worker.tail = nil
+	}
+
+	// Only consume complete records so that we never split a report that is still being written
+	consumed := bytes.LastIndex(data, worker.src.format.Separator)
+	if consumed < 0 {
+		consumed = 0
+	} else {
+		consumed += len(worker.src.format.Separator)
+	}
+	chunk := data[:consumed]
+
+	// Extract and deduplicate the raw reports out of the new data
+	reports, err := worker.src.format.Extract(bufio.NewReader(bytes.NewReader(chunk)))
+	if err != nil {
+		log.Error().Err(err).Msg("could not extract reports out of remote file")
+		return
+	}
+	reports = worker.normalize(reports)
+
+	// Load the reports we processed the previous time
+	state, err := source.LoadState(worker.stateFilePath)
+	if err != nil {
+		log.Error().Err(err).Msg("could not read current cycle state")
+		return
+	}
+
+	// Determine the new reports and the new state; a full download replaces the state while an incremental one extends
+	// it. Reports that disappeared from the file (e.g. because it was rotated) are never emitted again.
+	values := set.Added(reports, state).ToSlice()
+	if incremental {
+		for _, report := range values {
+			state.Add(report)
+		}
+	} else {
+		state = reports
+	}
+
+	// Emit the new reports before updating the state so that they are fetched again if they could not be accepted
+	if err := worker.emit(source.NewReports(worker.src.name, worker.file.path(), values)); err != nil {
+		log.Error().Err(err).Str("source", worker.src.name).Str("file", worker.file.path()).Msg("could not emit reports")
+		return
+	}
+	log.Debug().Str("source", worker.src.name).Bool("incremental", incremental).Int("bytes", len(data)).Int("amount", len(values)).Msg("emitted reports")
+
+	// Update the state
+	if err := source.SaveState(worker.stateFilePath, state); err != nil {
+		log.Error().Err(err).Msg("could not update current cycle state")
+		return
+	}
+
+	// Remember the position to resume from the next time
+	worker.offset = start + int64(consumed)
+	tail := append(append([]byte(nil), worker.tail...), chunk...)
+	if len(tail) > tailLength {
+		tail = tail[len(tail)-tailLength:]
+	}
+	worker.tail = tail
+	worker.file.commit()
+}
+
+// normalize normalizes every report of a set using the normalization function of the source format
+func (worker *cycleWorker) normalize(reports *set.HashSet[string]) *set.HashSet[string] {
+	if worker.src.format.Normalize == nil {
+		return reports
+	}
+	normalized := set.NewHashSet[string]()
+	for _, report := range reports.ToSlice() {
+		normalized.Add(worker.src.format.Normalize(report))
+	}
+	return normalized
+}
+
+// migrateState normalizes the reports of a state file written by a version that stored the raw reports
+func (worker *cycleWorker) migrateState() error {
+	state, err := source.LoadState(worker.stateFilePath)
+	if err != nil {
+		return err
+	}
+
+	normalized := worker.normalize(state)
+	if set.Added(normalized, state).Size() == 0 && normalized.Size() == state.Size() {
+		return nil
+	}
+	log.Info().Str("source", worker.src.name).Int("cycle", worker.cycle).Msg("migrating cycle state to normalized reports")
+	return source.SaveState(worker.stateFilePath, normalized)
+}
+
+func (worker *cycleWorker) stop() {
+	if worker.poller == nil {
+		return
+	}
+	worker.poller.Stop()
+	worker.poller = nil
+}
@@ -0,0 +1,183 @@
+package noaa
+
+import (
+	"github.com/skybi/nuntius/internal/metar"
+	"github.com/skybi/nuntius/internal/set"
+	"github.com/skybi/nuntius/internal/source"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+)
+
+// fakeFile serves the current content of a cycle file, always downloading it entirely unless stated otherwise
+type fakeFile struct {
+	content     string
+	incremental bool
+}
+
+func (file *fakeFile) path() string {
+	return "fake/00Z.TXT"
+}
+
+func (file *fakeFile) fetch(offset int64, _ []byte) ([]byte, int64, bool, error) {
+	if file.incremental && offset <= int64(len(file.content)) {
+		return []byte(file.content[offset:]), offset, false, nil
+	}
+	return []byte(file.content), 0, false, nil
+}
+
+func (file *fakeFile) commit() {
+}
+
+func newTestWorker(t *testing.T, file remoteFile) (*cycleWorker, *[]string) {
+	src := &CycleSource{
+		name: "test",
+		format: Format{
+			Extract:   metar.Extract,
+			Normalize: metar.Fix,
+			Separator: []byte("\n"),
+		},
+	}
+	emitted := new([]string)
+	worker := &cycleWorker{
+		src:           src,
+		file:          file,
+		stateFilePath: filepath.Join(t.TempDir(), "cycle-state-00"),
+		emit: func(reports []*source.Report) error {
+			*emitted = append(*emitted, source.Raws(reports)...)
+			return nil
+		},
+	}
+	return worker, emitted
+}
+
+func cycleFile(reports ...string) string {
+	var builder strings.Builder
+	for _, report := range reports {
+		builder.WriteString("2026/10/18 00:00\n")
+		builder.WriteString(report)
+		builder.WriteString("\n\n")
+	}
+	return builder.String()
+}
+
+func assertEmitted(t *testing.T, emitted *[]string, want ...string) {
+	t.Helper()
+	got := append([]string(nil), *emitted...)
+	sort.Strings(got)
+	sort.Strings(want)
+	if strings.Join(got, "|") != strings.Join(want, "|") {
+		t.Errorf("emitted %v, want %v", got, want)
+	}
+	*emitted = nil
+}
+
+func TestCycleWorkerRotation(t *testing.T) {
+	file := &fakeFile{content: cycleFile("EDDF 170020Z 24005KT CAVOK 10/05 Q1020", "EDDM 170020Z 27003KT CAVOK 09/04 Q1021")}
+	worker, emitted := newTestWorker(t, file)
+
+	worker.poll()
+	assertEmitted(t, emitted, "EDDF 170020Z 24005KT CAVOK 10/05 Q1020", "EDDM 170020Z 27003KT CAVOK 09/04 Q1021")
+
+	// The file gets rotated a day later and only contains a single new report
+	file.content = cycleFile("EDDF 180020Z 20008KT 9999 FEW030 11/06 Q1018")
+	worker.poll()
+	assertEmitted(t, emitted, "EDDF 180020Z 20008KT 9999 FEW030 11/06 Q1018")
+
+	// More reports are appended to the rotated file
+	file.content += cycleFile("EDDM 180020Z 25004KT CAVOK 10/03 Q1019")
+	worker.poll()
+	assertEmitted(t, emitted, "EDDM 180020Z 25004KT CAVOK 10/03 Q1019")
+
+	// Nothing changed
+	worker.poll()
+	assertEmitted(t, emitted)
+}
+
+func TestCycleWorkerIncremental(t *testing.T) {
+	file := &fakeFile{content: cycleFile("EDDF 170020Z 24005KT CAVOK 10/05 Q1020"), incremental: true}
+	worker, emitted := newTestWorker(t, file)
+
+	worker.poll()
+	assertEmitted(t, emitted, "EDDF 170020Z 24005KT CAVOK 10/05 Q1020")
+
+	// Duplicates appended to the file are not emitted again
+	file.content += cycleFile("EDDF 170020Z 24005KT CAVOK 10/05 Q1020", "EDDM 170020Z 27003KT CAVOK 09/04 Q1021")
+	worker.poll()
+	assertEmitted(t, emitted, "EDDM 170020Z 27003KT CAVOK 09/04 Q1021")
+
+	// An incomplete trailing record is only consumed once it was completed
+	file.content += "2026/10/18 00:00\nEDDH 170020Z 30010KT"
+	worker.poll()
+	assertEmitted(t, emitted)
+	file.content += " 9999 SCT020 08/02 Q1015\n"
+	worker.poll()
+	assertEmitted(t, emitted, "EDDH 170020Z 30010KT 9999 SCT020 08/02 Q1015")
+}
+
+func TestCycleWorkerNormalization(t *testing.T) {
+	file := &fakeFile{content: cycleFile("EDDF 170020Z 24005KT CAVOK 10/05 Q1020")}
+	worker, emitted := newTestWorker(t, file)
+
+	worker.poll()
+	assertEmitted(t, emitted, "EDDF 170020Z 24005KT CAVOK 10/05 Q1020")
+
+	// Reports only differing in their formatting are treated as equal
+	file.content += cycleFile("EDDF 170020  24005KT CAVOK  10/05 Q1020", "EDDM 170020Z 27003KT  CAVOK 09/04 Q1021")
+	worker.poll()
+	assertEmitted(t, emitted, "EDDM 170020Z 27003KT CAVOK 09/04 Q1021")
+}
+
+func TestCycleWorkerStateMigration(t *testing.T) {
+	file := &fakeFile{content: cycleFile("EDDF 170020Z 24005KT CAVOK 10/05 Q1020", "EDDM 170020Z 27003KT CAVOK 09/04 Q1021")}
+	worker, emitted := newTestWorker(t, file)
+
+	// Simulate a state file storing the raw reports
+	state := set.NewHashSet[string]()
+	state.Add("EDDF 170020  24005KT CAVOK 10/05 Q1020")
+	if err := source.SaveState(worker.stateFilePath, state); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := worker.migrateState(); err != nil {
+		t.Fatal(err)
+	}
+	migrated, err := source.LoadState(worker.stateFilePath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if migrated.Size() != 1 || !migrated.Contains("EDDF 170020Z 24005KT CAVOK 10/05 Q1020") {
+		t.Errorf("migrated state = %v", migrated.ToSlice())
+	}
+
+	worker.poll()
+	assertEmitted(t, emitted, "EDDM 170020Z 27003KT CAVOK 09/04 Q1021")
+}
+
+func TestCycleWorkerStartUnreadableState(t *testing.T) {
+	file := &fakeFile{content: cycleFile("EDDF 170020Z 24005KT CAVOK 10/05 Q1020")}
+	worker, emitted := newTestWorker(t, file)
+	worker.src.schedule = Schedule{PeakInterval: time.Hour, ActiveInterval: time.Hour, StaleInterval: time.Hour}
+
+	// A state that can not be read must not keep the worker from starting
+	if err := os.Mkdir(worker.stateFilePath, 0750); err != nil {
+		t.Fatal(err)
+	}
+	worker.start(worker.emit)
+	defer worker.stop()
+	if worker.poller == nil {
+		t.Fatal("worker did not start")
+	}
+
+	// Polling recovers as soon as the state is readable again
+	worker.poll()
+	assertEmitted(t, emitted)
+	if err := os.Remove(worker.stateFilePath); err != nil {
+		t.Fatal(err)
+	}
+	worker.poll()
+	assertEmitted(t, emitted, "EDDF 170020Z 24005KT CAVOK 10/05 Q1020")
+}
@@ -0,0 +1,253 @@
+package noaa
+
+import (
+	"errors"
+	"fmt"
+	"github.com/jlaffaye/ftp"
+	"github.com/rs/zerolog/log"
+	"github.com/skybi/nuntius/internal/backoff"
+	"net/textproto"
+	"sync"
+	"time"
+)
+
+// ErrBackingOff is returned when no connection is acquired because the pool waits before redialing the server
+var ErrBackingOff = errors.New("backing off from redialing the FTP server")
+
+// ConnState represents the state of the connection to the FTP server
+type ConnState int
+
+const (
+	ConnStateDisconnected ConnState = iota
+	ConnStateConnected
+	ConnStateReconnecting
+)
+
+func (state ConnState) String() string {
+	switch state {
+	case ConnStateConnected:
+		return "connected"
+	case ConnStateReconnecting:
+		return "reconnecting"
+	default:
+		return "disconnected"
+	}
+}
+
+// FTPConfig represents the configuration of the FTP server the cycle files are fetched from
+type FTPConfig struct {
+	Address     string
+	User        string
+	Password    string
+	BasePath    string
+	DialTimeout time.Duration
+}
+
+// FTPPool represents a pool of FTP connections shared between all cycle workers to limit the amount of open sessions
+type FTPPool struct {
+	sync.Mutex
+	config      FTPConfig
+	idleTimeout time.Duration
+
+	slots  chan struct{}
+	idle   []*pooledFTPConn
+	reaper *time.Timer
+
+	state    ConnState
+	backoff  backoff.Backoff
+	failures int
+	retryAt  time.Time
+}
+
+type pooledFTPConn struct {
+	conn     *ftp.ServerConn
+	lastUsed time.Time
+}
+
+// NewFTPPool creates a new FTP connection pool holding at most maxConns connections at the same time and closing
+// connections that were idle for longer than idleTimeout in the background.
+// Failing to dial the server makes the pool wait according to reconnectBackoff before trying it again.
+func NewFTPPool(config FTPConfig, maxConns int, idleTimeout time.Duration, reconnectBackoff backoff.Backoff) *FTPPool {
+	if maxConns < 1 {
+		maxConns = 1
+	}
+	return &FTPPool{
+		config:      config,
+		idleTimeout: idleTimeout,
+		slots:       make(chan struct{}, maxConns),
+		state:       ConnStateDisconnected,
+		backoff:     reconnectBackoff,
+	}
+}
+
+// Config returns the configuration of the FTP server the pool connects to
+func (pool *FTPPool) Config() FTPConfig {
+	return pool.config
+}
+
+// Acquire borrows a healthy connection out of the pool, dialing a new one if no idle one is available.
+// It blocks until a connection slot is free; the connection has to be given back using Release afterwards.
+func (pool *FTPPool) Acquire() (*ftp.ServerConn, error) {
+	pool.slots <- struct{}{}
+
+	for {
+		pooled := pool.popIdle()
+		if pooled == nil {
+			break
+		}
+		if err := pooled.conn.NoOp(); err != nil {
+			pooled.conn.Quit()
+			pool.markBroken()
+			continue
+		}
+		return pooled.conn, nil
+	}
+
+	if wait := pool.retryDelay(); wait > 0 {
+		<-pool.slots
+		return nil, fmt.Errorf("%w (retrying in %s)", ErrBackingOff, wait.Round(time.Millisecond))
+	}
+
+	conn, err := pool.dial()
+	if err != nil {
+		pool.markDialFailed()
+		<-pool.slots
+		return nil, err
+	}
+	pool.setState(ConnStateConnected)
+	return conn, nil
+}
+
+// Release gives a connection back to the pool; broken connections are closed instead of being reused
+func (pool *FTPPool) Release(conn *ftp.ServerConn, broken bool) {
+	defer func() {
+		<-pool.slots
+	}()
+
+	if broken {
+		conn.Quit()
+		pool.markBroken()
+		return
+	}
+
+	pool.Lock()
+	defer pool.Unlock()
+	pool.idle = append(pool.idle, &pooledFTPConn{
+		conn:     conn,
+		lastUsed: time.Now(),
+	})
+	if pool.reaper == nil {
+		pool.reaper = time.AfterFunc(pool.idleTimeout, pool.reap)
+	}
+}
+
+// Close closes all idle connections of the pool
+func (pool *FTPPool) Close() {
+	pool.Lock()
+	defer pool.Unlock()
+	if pool.reaper != nil {
+		pool.reaper.Stop()
+		pool.reaper = nil
+	}
+	for _, pooled := range pool.idle {
+		pooled.conn.Quit()
+	}
+	pool.idle = nil
+}
+
+func (pool *FTPPool) retryDelay() time.Duration {
+	pool.Lock()
+	defer pool.Unlock()
+	return time.Until(pool.retryAt)
+}
+
+func (pool *FTPPool) markBroken() {
+	pool.Lock()
+	defer pool.Unlock()
+	pool.unsafeSetState(ConnStateReconnecting)
+}
+
+func (pool *FTPPool) markDialFailed() {
+	pool.Lock()
+	defer pool.Unlock()
+	pool.failures++
+	delay := pool.backoff.Delay(pool.failures)
+	pool.retryAt = time.Now().Add(delay)
+	if pool.state == ConnStateConnected {
+		pool.unsafeSetState(ConnStateReconnecting)
+	}
+	log.Warn().Int("failures", pool.failures).Dur("retry_in", delay).Msg("could not dial FTP server; backing off")
+}
+
+func (pool *FTPPool) setState(state ConnState) {
+	pool.Lock()
+	defer pool.Unlock()
+	if state == ConnStateConnected {
+		pool.failures = 0
+		pool.retryAt = time.Time{}
+	}
+	pool.unsafeSetState(state)
+}
+
+func (pool *FTPPool) unsafeSetState(state ConnState) {
+	if pool.state == state {
+		return
+	}
+	log.Info().Str("from", pool.state.String()).Str("to", state.String()).Msg("FTP connection state changed")
+	pool.state = state
+}
+
+// reap closes the idle connections that exceeded the idle timeout and reschedules itself for the oldest remaining one
+func (pool *FTPPool) reap() {
+	pool.Lock()
+	defer pool.Unlock()
+	pool.unsafeCloseExpired()
+	if len(pool.idle) == 0 {
+		pool.reaper = nil
+		return
+	}
+	if pool.reaper != nil {
+		pool.reaper.Reset(pool.idleTimeout - time.Since(pool.idle[0].lastUsed))
+	}
+}
+
+// unsafeCloseExpired closes the connections that were idle for too long; the most recently used ones are at the end
+// of the slice
+func (pool *FTPPool) unsafeCloseExpired() {
+	for len(pool.idle) > 0 && time.Since(pool.idle[0].lastUsed) >= pool.idleTimeout {
+		pool.idle[0].conn.Quit()
+		pool.idle = pool.idle[1:]
+	}
+}
+
+func (pool *FTPPool) popIdle() *pooledFTPConn {
+	pool.Lock()
+	defer pool.Unlock()
+
+	pool.unsafeCloseExpired()
+	if len(pool.idle) == 0 {
+		return nil
+	}
+	pooled := pool.idle[len(pool.idle)-1]
+	pool.idle = pool.idle[:len(pool.idle)-1]
+	return pooled
+}
+
+func (pool *FTPPool) dial() (*ftp.ServerConn, error) {
+	ftpConn, err := ftp.Dial(pool.config.Address, ftp.DialWithTimeout(pool.config.DialTimeout))
+	if err != nil {
+		return nil, err
+	}
+	if err := ftpConn.Login(pool.config.User, pool.config.Password); err != nil {
+		ftpConn.Quit()
+		return nil, err
+	}
+	return ftpConn, nil
+}
+
+// isConnError checks whether an error returned by an FTP operation indicates a broken connection as opposed to a
+// regular negative server reply
+func isConnError(err error) bool {
+	var protoErr *textproto.Error
+	return err != nil && !errors.As(err, &protoErr)
+}
@@ -0,0 +1,97 @@
+package noaa
+
+import (
+	"bufio"
+	"fmt"
+	"github.com/skybi/nuntius/internal/backoff"
+	"net"
+	"strings"
+	"testing"
+	"time"
+)
+
+// serveFakeFTP accepts FTP sessions on a local listener, answering just enough commands to log in and signaling
+// every received QUIT on the returned channel
+func serveFakeFTP(t *testing.T) (string, <-chan struct{}) {
+	t.Helper()
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		listener.Close()
+	})
+
+	quits := make(chan struct{}, 8)
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer conn.Close()
+				fmt.Fprint(conn, "220 ready\r\n")
+				scanner := bufio.NewScanner(conn)
+				for scanner.Scan() {
+					command := strings.Fields(scanner.Text())
+					if len(command) == 0 {
+						continue
+					}
+					switch strings.ToUpper(command[0]) {
+					case "USER":
+						fmt.Fprint(conn, "230 logged in\r\n")
+					case "FEAT":
+						fmt.Fprint(conn, "211 no features\r\n")
+					case "TYPE", "NOOP":
+						fmt.Fprint(conn, "200 ok\r\n")
+					case "QUIT":
+						fmt.Fprint(conn, "221 bye\r\n")
+						quits <- struct{}{}
+						return
+					default:
+						fmt.Fprint(conn, "502 not implemented\r\n")
+					}
+				}
+			}()
+		}
+	}()
+	return listener.Addr().String(), quits
+}
+
+func TestFTPPoolReapsIdleConnections(t *testing.T) {
+	address, quits := serveFakeFTP(t)
+	pool := NewFTPPool(FTPConfig{Address: address, DialTimeout: time.Second}, 2, 50*time.Millisecond, backoff.Backoff{})
+	defer pool.Close()
+
+	conn, err := pool.Acquire()
+	if err != nil {
+		t.Fatalf("Acquire() error = %v", err)
+	}
+	pool.Release(conn, false)
+
+	// The idle connection is closed without the pool being used again
+	select {
+	case <-quits:
+	case <-time.After(2 * time.Second):
+		t.Fatal("idle connection was not closed after the idle timeout")
+	}
+	pool.Lock()
+	idle, reaper := len(pool.idle), pool.reaper
+	pool.Unlock()
+	if idle != 0 || reaper != nil {
+		t.Errorf("pool holds %d idle connections and reaper %v after reaping, want none", idle, reaper)
+	}
+
+	// Connections released afterwards are reaped as well
+	conn, err = pool.Acquire()
+	if err != nil {
+		t.Fatalf("Acquire() error = %v", err)
+	}
+	pool.Release(conn, false)
+	select {
+	case <-quits:
+	case <-time.After(2 * time.Second):
+		t.Fatal("connection released after reaping was not closed after the idle timeout")
+	}
+}
@@ -0,0 +1,132 @@
+package noaa
+
+import (
+	"bytes"
+	"github.com/jlaffaye/ftp"
+	"github.com/rs/zerolog/log"
+	"io"
+	"path"
+)
+
+// FTPTransport represents a transport accessing the cycle files using a shared FTP connection pool
+type FTPTransport struct {
+	pool            *FTPPool
+	changeDetection ChangeDetection
+}
+
+var _ Transport = (*FTPTransport)(nil)
+
+// NewFTPTransport creates a new FTP transport detecting changes of the cycle files using the given strategy
+func NewFTPTransport(pool *FTPPool, changeDetection ChangeDetection) *FTPTransport {
+	return &FTPTransport{
+		pool:            pool,
+		changeDetection: changeDetection,
+	}
+}
+
+func (transport *FTPTransport) file(filePath string) remoteFile {
+	return &ftpFile{
+		transport:  transport,
+		remotePath: path.Join(transport.pool.Config().BasePath, filePath),
+	}
+}
+
+type ftpFile struct {
+	transport  *FTPTransport
+	remotePath string
+
+	// lastFingerprint and lastContentHash identify the version of the file that was committed the previous time
+	lastFingerprint    string
+	lastContentHash    string
+	pendingFingerprint string
+	pendingContentHash string
+}
+
+func (file *ftpFile) path() string {
+	return file.remotePath
+}
+
+func (file *ftpFile) fetch(offset int64, tail []byte) ([]byte, int64, bool, error) {
+	ftpConn, err := file.transport.pool.Acquire()
+	if err != nil {
+		return nil, 0, false, err
+	}
+	broken := false
+	defer func() {
+		file.transport.pool.Release(ftpConn, broken)
+	}()
+
+	// We only want to process files that were modified since we processed them the previous time
+	fingerprint, err := remoteFingerprint(file.transport.changeDetection, ftpConn, file.remotePath)
+	if err != nil {
+		broken = isConnError(err)
+		return nil, 0, false, err
+	}
+	if fingerprint != "" && fingerprint == file.lastFingerprint {
+		return nil, 0, true, nil
+	}
+
+	// Download the part of the file we did not consume yet
+	data, start, err := file.download(ftpConn, offset, tail)
+	if err != nil {
+		broken = isConnError(err)
+		return nil, 0, false, err
+	}
+
+	// Some strategies can only detect changes by looking at the contents
+	contentHash := contentFingerprint(file.transport.changeDetection, start, data)
+	if contentHash != "" && contentHash == file.lastContentHash {
+		file.lastFingerprint = fingerprint
+		return nil, 0, true, nil
+	}
+
+	file.pendingFingerprint = fingerprint
+	file.pendingContentHash = contentHash
+	return data, start, false, nil
+}
+
+func (file *ftpFile) commit() {
+	file.lastFingerprint = file.pendingFingerprint
+	file.lastContentHash = file.pendingContentHash
+}
+
+// download downloads the remote file starting at the already consumed offset.
+// It falls back to downloading the whole file if it shrunk or does not end with the bytes consumed the previous time.
+func (file *ftpFile) download(ftpConn *ftp.ServerConn, offset int64, tail []byte) ([]byte, int64, error) {
+	if offset > 0 {
+		size, err := ftpConn.FileSize(file.remotePath)
+		switch {
+		case err != nil && isConnError(err):
+			return nil, 0, err
+		case err != nil:
+			log.Debug().Err(err).Str("file", file.remotePath).Msg("could not determine file size; downloading the whole file")
+		case size < offset:
+			log.Debug().Str("file", file.remotePath).Msg("remote file shrunk; downloading the whole file")
+		default:
+			data, err := retrieve(ftpConn, file.remotePath, offset-int64(len(tail)))
+			if err != nil {
+				return nil, 0, err
+			}
+			if bytes.HasPrefix(data, tail) {
+				return data[len(tail):], offset, nil
+			}
+			log.Debug().Str("file", file.remotePath).Msg("remote file was rotated; downloading the whole file")
+		}
+	}
+
+	data, err := retrieve(ftpConn, file.remotePath, 0)
+	return data, 0, err
+}
+
+func retrieve(ftpConn *ftp.ServerConn, path string, offset int64) ([]byte, error) {
+	reader, err := ftpConn.RetrFrom(path, uint64(offset))
+	if err != nil {
+		return nil, err
+	}
+	data, err := io.ReadAll(reader)
+	if err != nil {
+		reader.Close()
+		return nil, err
+	}
+	return data, reader.Close()
+}
@@ -0,0 +1,148 @@
+package noaa
+
+import (
+	"bytes"
+	"fmt"
+	"github.com/rs/zerolog/log"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+)
+
+// HTTPTransport represents a transport accessing the cycle files using an HTTP(S) mirror of the FTP data server
+type HTTPTransport struct {
+	sync.Mutex
+	baseURL string
+	client  *http.Client
+	state   ConnState
+}
+
+var _ Transport = (*HTTPTransport)(nil)
+
+// NewHTTPTransport creates a new HTTP transport accessing the cycle files relative to baseURL
+func NewHTTPTransport(baseURL string, client *http.Client) *HTTPTransport {
+	for strings.HasSuffix(baseURL, "/") {
+		baseURL = strings.TrimSuffix(baseURL, "/")
+	}
+	return &HTTPTransport{
+		baseURL: baseURL,
+		client:  client,
+		state:   ConnStateDisconnected,
+	}
+}
+
+func (transport *HTTPTransport) file(filePath string) remoteFile {
+	return &httpFile{
+		transport: transport,
+		url:       transport.baseURL + "/" + strings.TrimPrefix(filePath, "/"),
+	}
+}
+
+func (transport *HTTPTransport) setState(state ConnState) {
+	transport.Lock()
+	defer transport.Unlock()
+	if transport.state == state {
+		return
+	}
+	log.Info().Str("from", transport.state.String()).Str("to", state.String()).Msg("HTTP connection state changed")
+	transport.state = state
+}
+
+type httpFile struct {
+	transport *HTTPTransport
+	url       string
+
+	// etag and lastModified are the validators of the version of the file that was committed the previous time
+	etag                string
+	lastModified        string
+	pendingETag         string
+	pendingLastModified string
+}
+
+func (file *httpFile) path() string {
+	return file.url
+}
+
+func (file *httpFile) fetch(offset int64, tail []byte) ([]byte, int64, bool, error) {
+	if offset > 0 {
+		data, status, err := file.get(offset-int64(len(tail)), true)
+		if err != nil {
+			return nil, 0, false, err
+		}
+		switch status {
+		case http.StatusNotModified:
+			return nil, 0, true, nil
+		case http.StatusOK:
+			// The server ignored the range
+			return data, 0, false, nil
+		case http.StatusPartialContent:
+			if bytes.HasPrefix(data, tail) {
+				return data[len(tail):], offset, false, nil
+			}
+			log.Debug().Str("file", file.url).Msg("remote file was rotated; downloading the whole file")
+		case http.StatusRequestedRangeNotSatisfiable:
+			log.Debug().Str("file", file.url).Msg("remote file shrunk; downloading the whole file")
+		}
+	}
+
+	// The validators only apply if we did not detect a rotation above
+	data, status, err := file.get(0, offset == 0)
+	if err != nil {
+		return nil, 0, false, err
+	}
+	if status == http.StatusNotModified {
+		return nil, 0, true, nil
+	}
+	if status != http.StatusOK {
+		return nil, 0, false, fmt.Errorf("unexpected HTTP status %d", status)
+	}
+	return data, 0, false, nil
+}
+
+func (file *httpFile) commit() {
+	file.etag = file.pendingETag
+	file.lastModified = file.pendingLastModified
+}
+
+// get requests the file starting at start, optionally only if it changed since the last commit
+func (file *httpFile) get(start int64, conditional bool) ([]byte, int, error) {
+	request, err := http.NewRequest(http.MethodGet, file.url, nil)
+	if err != nil {
+		return nil, 0, err
+	}
+	if conditional {
+		if file.etag != "" {
+			request.Header.Set("If-None-Match", file.etag)
+		}
+		if file.lastModified != "" {
+			request.Header.Set("If-Modified-Since", file.lastModified)
+		}
+	}
+	if start > 0 {
+		request.Header.Set("Range", fmt.Sprintf("bytes=%d-", start))
+	}
+
+	response, err := file.transport.client.Do(request)
+	if err != nil {
+		file.transport.setState(ConnStateReconnecting)
+		return nil, 0, err
+	}
+	defer response.Body.Close()
+	file.transport.setState(ConnStateConnected)
+
+	switch response.StatusCode {
+	case http.StatusOK, http.StatusPartialContent:
+		data, err := io.ReadAll(response.Body)
+		if err != nil {
+			return nil, 0, err
+		}
+		file.pendingETag = response.Header.Get("ETag")
+		file.pendingLastModified = response.Header.Get("Last-Modified")
+		return data, response.StatusCode, nil
+	case http.StatusNotModified, http.StatusRequestedRangeNotSatisfiable:
+		return nil, response.StatusCode, nil
+	default:
+		return nil, 0, fmt.Errorf("HTTP status %d", response.StatusCode)
+	}
+}
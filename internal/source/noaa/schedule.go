@@ -0,0 +1,66 @@
+package noaa
+
+import (
+	"math/rand"
+	"time"
+)
+
+// Schedule describes how often the cycle files are polled depending on whether they currently receive new reports
+type Schedule struct {
+	// ActiveInterval is used for the cycles of the current and previous UTC hour
+	ActiveInterval time.Duration
+
+	// PeakInterval is used for the active cycles during the issuance window around the full hour (HH:50-HH:05)
+	PeakInterval time.Duration
+
+	// StaleInterval is used for all other cycles
+	StaleInterval time.Duration
+
+	// Jitter is the fraction (0-1) an interval may randomly deviate by to stagger the requests of multiple cycles
+	Jitter float64
+}
+
+// next calculates the time to wait before polling the given cycle the next time
+func (schedule Schedule) next(cycle int, now time.Time) time.Duration {
+	now = now.UTC()
+	hour, minute := now.Hour(), now.Minute()
+	peak := minute >= 50 || minute < 5
+
+	var interval time.Duration
+	switch {
+	case isActiveCycle(cycle, hour, minute) && peak:
+		interval = schedule.PeakInterval
+	case isActiveCycle(cycle, hour, minute):
+		interval = schedule.ActiveInterval
+	default:
+		// Make sure to not oversleep the moment the cycle becomes active
+		interval = schedule.StaleInterval
+		if untilActive := timeUntilActive(cycle, now); untilActive < interval {
+			interval = untilActive
+		}
+	}
+
+	if schedule.Jitter > 0 {
+		interval += time.Duration((rand.Float64()*2 - 1) * schedule.Jitter * float64(interval))
+	}
+	if interval < time.Second {
+		interval = time.Second
+	}
+	return interval
+}
+
+// isActiveCycle checks whether a cycle receives new reports at the given time.
+// These are the cycles of the current and previous hour and, from HH:45 on, the one of the next hour as the reports
+// issued shortly before the full hour belong to it.
+func isActiveCycle(cycle, hour, minute int) bool {
+	return cycle == hour || cycle == (hour+23)%24 || (minute >= 45 && cycle == (hour+1)%24)
+}
+
+// timeUntilActive calculates the time until the given cycle becomes active again, i.e. until HH:45 of the hour before
+func timeUntilActive(cycle int, now time.Time) time.Duration {
+	activation := time.Date(now.Year(), now.Month(), now.Day(), (cycle+23)%24, 45, 0, 0, time.UTC)
+	for !activation.After(now) {
+		activation = activation.Add(24 * time.Hour)
+	}
+	return activation.Sub(now)
+}
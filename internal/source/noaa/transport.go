@@ -0,0 +1,20 @@
+package noaa
+
+// Transport represents the way the cycle files are accessed, i.e. FTP or HTTP
+type Transport interface {
+	file(path string) remoteFile
+}
+
+// remoteFile represents a single cycle file accessed over a specific transport
+type remoteFile interface {
+	// path returns the location of the file used for provenance and logging
+	path() string
+
+	// fetch downloads the part of the file following offset, including the already consumed tail right before it.
+	// The returned data starts at start, which is 0 if the file had to be downloaded entirely because it shrunk or
+	// its tail changed, i.e. it was rotated. unchanged is true if the file did not change since the last commit.
+	fetch(offset int64, tail []byte) (data []byte, start int64, unchanged bool, err error)
+
+	// commit marks the data returned by the previous fetch as processed
+	commit()
+}
@@ -0,0 +1,57 @@
+package source
+
+import "time"
+
+// Poller executes a polling function in the background until it gets stopped, waiting the interval returned by a
+// scheduling function before each execution
+type Poller struct {
+	next func() time.Duration
+	poll func()
+
+	running  bool
+	stopChan chan struct{}
+}
+
+// NewPoller creates a new poller executing poll every interval
+func NewPoller(interval time.Duration, poll func()) *Poller {
+	return NewScheduledPoller(func() time.Duration {
+		return interval
+	}, poll)
+}
+
+// NewScheduledPoller creates a new poller executing poll after every interval returned by next
+func NewScheduledPoller(next func() time.Duration, poll func()) *Poller {
+	return &Poller{
+		next: next,
+		poll: poll,
+	}
+}
+
+// Start starts the polling task
+func (poller *Poller) Start() {
+	if poller.running {
+		return
+	}
+
+	poller.running = true
+	poller.stopChan = make(chan struct{})
+	go func(stopChan chan struct{}) {
+		for {
+			select {
+			case <-stopChan:
+				return
+			case <-time.After(poller.next()):
+				poller.poll()
+			}
+		}
+	}(poller.stopChan)
+}
+
+// Stop stops the polling task
+func (poller *Poller) Stop() {
+	if !poller.running {
+		return
+	}
+	close(poller.stopChan)
+	poller.running = false
+}
@@ -0,0 +1,118 @@
+package push
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"github.com/rs/zerolog/log"
+	"github.com/skybi/nuntius/internal/source"
+	"mime"
+	"net/http"
+	"strings"
+	"sync"
+)
+
+// Endpoint represents a source receiving the reports pushed to a single ingest endpoint of a Server
+type Endpoint struct {
+	sync.RWMutex
+	name      string
+	mediaType string
+	extract   source.ExtractFunc
+	emit      source.EmitFunc
+}
+
+var _ source.Source = (*Endpoint)(nil)
+
+// Endpoint registers a new endpoint at /ingest/<kind> which parses bodies of the given media type using extract.
+// Requests are rejected until the endpoint is started.
+func (srv *Server) Endpoint(kind, mediaType string, extract source.ExtractFunc) *Endpoint {
+	srv.Lock()
+	defer srv.Unlock()
+	endpoint := &Endpoint{
+		name:      "push-" + kind,
+		mediaType: mediaType,
+		extract:   extract,
+	}
+	srv.endpoints[kind] = endpoint
+	return endpoint
+}
+
+// Name returns the name of the source
+func (endpoint *Endpoint) Name() string {
+	return endpoint.name
+}
+
+// Start starts accepting reports
+func (endpoint *Endpoint) Start(emit source.EmitFunc) error {
+	endpoint.Lock()
+	defer endpoint.Unlock()
+	endpoint.emit = emit
+	return nil
+}
+
+// Stop stops accepting reports
+func (endpoint *Endpoint) Stop() {
+	endpoint.Lock()
+	defer endpoint.Unlock()
+	endpoint.emit = nil
+}
+
+func (endpoint *Endpoint) handle(writer http.ResponseWriter, request *http.Request) {
+	endpoint.RLock()
+	emit := endpoint.emit
+	endpoint.RUnlock()
+	if emit == nil {
+		writeError(writer, http.StatusServiceUnavailable, "ingest.unavailable", "endpoint is not accepting reports")
+		return
+	}
+
+	// Parse the reports either out of a JSON body of the same structure the data API expects, whose items are in the
+	// endpoint's native format, or out of a body in the native format itself
+	var raws []string
+	mediaType, _, _ := mime.ParseMediaType(request.Header.Get("Content-Type"))
+	if mediaType == "" {
+		mediaType = endpoint.mediaType
+	}
+	switch mediaType {
+	case "application/json":
+		body := new(struct {
+			Data []string `json:"data"`
+		})
+		if err := json.NewDecoder(request.Body).Decode(body); err != nil {
+			writeError(writer, http.StatusBadRequest, "ingest.invalidBody", err.Error())
+			return
+		}
+		for i, item := range body.Data {
+			if item = strings.TrimSpace(item); item == "" {
+				continue
+			}
+			extracted, err := endpoint.extract(bufio.NewReader(strings.NewReader(item)))
+			if err != nil {
+				writeError(writer, http.StatusBadRequest, "ingest.invalidBody", fmt.Sprintf("item %d: %v", i, err))
+				return
+			}
+			raws = append(raws, extracted.ToSlice()...)
+		}
+	case endpoint.mediaType:
+		reports, err := endpoint.extract(bufio.NewReader(request.Body))
+		if err != nil {
+			writeError(writer, http.StatusBadRequest, "ingest.invalidBody", err.Error())
+			return
+		}
+		raws = reports.ToSlice()
+	default:
+		writeError(writer, http.StatusUnsupportedMediaType, "ingest.unsupportedMediaType", "only "+endpoint.mediaType+" and application/json are supported")
+		return
+	}
+
+	if err := emit(source.NewReports(endpoint.name, request.RemoteAddr, raws)); err != nil {
+		log.Error().Err(err).Str("source", endpoint.name).Msg("could not emit reports")
+		writeError(writer, http.StatusServiceUnavailable, "ingest.notAccepted", "reports could not be queued")
+		return
+	}
+	log.Debug().Str("source", endpoint.name).Str("remote", request.RemoteAddr).Int("amount", len(raws)).Msg("emitted reports")
+
+	writeJSON(writer, http.StatusAccepted, map[string]int{
+		"accepted": len(raws),
+	})
+}
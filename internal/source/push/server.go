@@ -0,0 +1,132 @@
+package push
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"github.com/rs/zerolog/log"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// maxBodySize is the maximum size of a single ingest request body
+const maxBodySize = 4 << 20
+
+// Server represents the HTTP server accepting reports pushed to the ingest endpoints
+type Server struct {
+	sync.RWMutex
+	key       string
+	server    *http.Server
+	endpoints map[string]*Endpoint
+	running   bool
+}
+
+// NewServer creates a new ingest server listening on address and requiring the given bearer token
+func NewServer(address, key string) *Server {
+	srv := &Server{
+		key:       key,
+		endpoints: make(map[string]*Endpoint),
+	}
+	srv.server = &http.Server{
+		Addr:              address,
+		Handler:           http.HandlerFunc(srv.handle),
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+	return srv
+}
+
+// Start binds the address and serves requests in the background
+func (srv *Server) Start() error {
+	if srv.running {
+		return nil
+	}
+	if srv.key == "" {
+		return errors.New("an ingest key is required")
+	}
+
+	// Bind synchronously so that an unusable address is reported to the caller
+	listener, err := net.Listen("tcp", srv.server.Addr)
+	if err != nil {
+		return err
+	}
+
+	srv.running = true
+	go func() {
+		if err := srv.server.Serve(listener); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			log.Error().Err(err).Str("address", srv.server.Addr).Msg("ingest server stopped unexpectedly")
+		}
+	}()
+	return nil
+}
+
+// Stop gracefully shuts down the server
+func (srv *Server) Stop() error {
+	if !srv.running {
+		return nil
+	}
+	srv.running = false
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	return srv.server.Shutdown(ctx)
+}
+
+func (srv *Server) handle(writer http.ResponseWriter, request *http.Request) {
+	if !strings.HasPrefix(request.URL.Path, "/ingest/") {
+		writeError(writer, http.StatusNotFound, "ingest.notFound", "endpoint not found")
+		return
+	}
+	if request.Method != http.MethodPost {
+		writeError(writer, http.StatusMethodNotAllowed, "ingest.methodNotAllowed", "only POST is allowed")
+		return
+	}
+
+	scheme, token, ok := strings.Cut(request.Header.Get("Authorization"), " ")
+	if !ok || !strings.EqualFold(scheme, "Bearer") || subtle.ConstantTimeCompare([]byte(token), []byte(srv.key)) != 1 {
+		writeError(writer, http.StatusUnauthorized, "ingest.unauthorized", "missing or invalid ingest key")
+		return
+	}
+
+	srv.RLock()
+	endpoint, ok := srv.endpoints[strings.TrimPrefix(request.URL.Path, "/ingest/")]
+	srv.RUnlock()
+	if !ok {
+		writeError(writer, http.StatusNotFound, "ingest.notFound", "endpoint not found")
+		return
+	}
+
+	request.Body = http.MaxBytesReader(writer, request.Body, maxBodySize)
+	endpoint.handle(writer, request)
+}
+
+type errorResponse struct {
+	Status int             `json:"status"`
+	Errors []*errorMessage `json:"errors"`
+}
+
+type errorMessage struct {
+	Type    string `json:"type"`
+	Message string `json:"message"`
+}
+
+func writeJSON(writer http.ResponseWriter, status int, value any) {
+	writer.Header().Set("Content-Type", "application/json")
+	writer.WriteHeader(status)
+	_ = json.NewEncoder(writer).Encode(value)
+}
+
+func writeError(writer http.ResponseWriter, status int, errType, message string) {
+	writeJSON(writer, status, &errorResponse{
+		Status: status,
+		Errors: []*errorMessage{
+			{
+				Type:    errType,
+				Message: message,
+			},
+		},
+	})
+}
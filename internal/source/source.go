@@ -0,0 +1,68 @@
+package source
+
+import (
+	"bufio"
+	"github.com/skybi/nuntius/internal/set"
+	"time"
+)
+
+// Report represents a single raw report emitted by a source together with its provenance
+type Report struct {
+	Raw      string
+	Source   string
+	Origin   string
+	Received time.Time
+}
+
+// NewReports wraps multiple raw reports originating from the same place into Report structures
+func NewReports(source, origin string, raws []string) []*Report {
+	now := time.Now()
+	reports := make([]*Report, 0, len(raws))
+	for _, raw := range raws {
+		reports = append(reports, &Report{
+			Raw:      raw,
+			Source:   source,
+			Origin:   origin,
+			Received: now,
+		})
+	}
+	return reports
+}
+
+// Raws extracts the raw strings out of multiple reports
+func Raws(reports []*Report) []string {
+	raws := make([]string, 0, len(reports))
+	for _, report := range reports {
+		raws = append(raws, report.Raw)
+	}
+	return raws
+}
+
+// WithKeyword returns the raw text a METAR or SPECI parsed out of a format stating its keyword is emitted as. SPECIs keep
+// their keyword so that they stay distinguishable from METARs, while the keyword of METARs is dropped like in the NOAA
+// cycle files.
+func WithKeyword(keyword, raw string) string {
+	if keyword == "SPECI" {
+		return keyword + " " + raw
+	}
+	return raw
+}
+
+// ExtractFunc extracts and deduplicates the raw reports out of a text file
+type ExtractFunc func(reader *bufio.Reader) (*set.HashSet[string], error)
+
+// EmitFunc is called by a source whenever it encountered new reports. If it returns an error, the reports were not
+// accepted and the source has to emit them again later on.
+type EmitFunc func(reports []*Report) error
+
+// Source represents an origin of reports which collects them in the background while it is running
+type Source interface {
+	// Name returns the unique name of the source used for provenance and logging
+	Name() string
+
+	// Start starts collecting reports and emitting them to the given function
+	Start(emit EmitFunc) error
+
+	// Stop stops collecting reports
+	Stop()
+}
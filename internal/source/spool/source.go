@@ -0,0 +1,180 @@
+package spool
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"github.com/rs/zerolog/log"
+	"github.com/skybi/nuntius/internal/source"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+const (
+	doneDir   = "done"
+	failedDir = "failed"
+)
+
+// errNotAccepted is returned if the reports of a spool file were extracted but not accepted by the emit function
+var errNotAccepted = errors.New("reports were not accepted")
+
+// Source represents a source ingesting the reports contained in text files dropped into a spool directory.
+// Processed files are moved into the done or failed subdirectory.
+type Source struct {
+	dir      string
+	extract  source.ExtractFunc
+	interval time.Duration
+	settle   time.Duration
+
+	emit     source.EmitFunc
+	running  bool
+	stopChan chan struct{}
+}
+
+var _ source.Source = (*Source)(nil)
+
+// New creates a new spool source watching dir and additionally scanning it every interval.
+// Files are only picked up if they were not modified for the settle duration to not read files still being written;
+// files whose name starts with a dot are always ignored.
+func New(dir string, extract source.ExtractFunc, interval, settle time.Duration) *Source {
+	return &Source{
+		dir:      dir,
+		extract:  extract,
+		interval: interval,
+		settle:   settle,
+	}
+}
+
+// Name returns the name of the source
+func (src *Source) Name() string {
+	return "spool"
+}
+
+// Start starts watching the spool directory
+func (src *Source) Start(emit source.EmitFunc) error {
+	if src.running {
+		return nil
+	}
+
+	abs, err := filepath.Abs(src.dir)
+	if err != nil {
+		return err
+	}
+	src.dir = abs
+	for _, dir := range []string{src.dir, filepath.Join(src.dir, doneDir), filepath.Join(src.dir, failedDir)} {
+		if err := os.MkdirAll(dir, 0750); err != nil && !errors.Is(err, os.ErrExist) {
+			return err
+		}
+	}
+
+	src.emit = emit
+	src.running = true
+	src.stopChan = make(chan struct{})
+
+	events, err := watch(src.dir, src.stopChan)
+	if err != nil {
+		log.Warn().Err(err).Str("dir", src.dir).Msg("could not watch spool directory; falling back to polling")
+	}
+
+	go func(stopChan chan struct{}) {
+		src.scan()
+
+		// Files reported by the watcher are scanned as soon as they settled
+		var settled <-chan time.Time
+		for {
+			select {
+			case <-stopChan:
+				return
+			case _, ok := <-events:
+				if !ok {
+					events = nil
+					continue
+				}
+				if settled == nil {
+					settled = time.After(src.settle)
+				}
+			case <-settled:
+				settled = nil
+				src.scan()
+			case <-time.After(src.interval):
+				src.scan()
+			}
+		}
+	}(src.stopChan)
+	return nil
+}
+
+// Stop stops watching the spool directory
+func (src *Source) Stop() {
+	if !src.running {
+		return
+	}
+	close(src.stopChan)
+	src.running = false
+}
+
+func (src *Source) scan() {
+	entries, err := os.ReadDir(src.dir)
+	if err != nil {
+		log.Error().Err(err).Str("dir", src.dir).Msg("could not read spool directory")
+		return
+	}
+
+	for _, entry := range entries {
+		if !entry.Type().IsRegular() || strings.HasPrefix(entry.Name(), ".") {
+			continue
+		}
+		info, err := entry.Info()
+		if err != nil || time.Since(info.ModTime()) < src.settle {
+			continue
+		}
+
+		path := filepath.Join(src.dir, entry.Name())
+		if err := src.process(path); err != nil {
+			if errors.Is(err, errNotAccepted) {
+				// Leave the file in place to process it again during the next scan
+				log.Error().Err(err).Str("file", path).Msg("could not emit reports of spool file")
+				continue
+			}
+			log.Error().Err(err).Str("file", path).Msg("could not process spool file")
+			if err := moveInto(path, filepath.Join(src.dir, failedDir)); err != nil {
+				log.Error().Err(err).Str("file", path).Msg("could not move spool file to failed directory")
+			}
+			continue
+		}
+		if err := moveInto(path, filepath.Join(src.dir, doneDir)); err != nil {
+			log.Error().Err(err).Str("file", path).Msg("could not move spool file to done directory")
+		}
+	}
+}
+
+func (src *Source) process(path string) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	reports, err := src.extract(bufio.NewReader(file))
+	if err != nil {
+		return err
+	}
+
+	values := reports.ToSlice()
+	if err := src.emit(source.NewReports(src.Name(), path, values)); err != nil {
+		return fmt.Errorf("%w: %v", errNotAccepted, err)
+	}
+	log.Debug().Str("source", src.Name()).Str("file", path).Int("amount", len(values)).Msg("emitted reports")
+	return nil
+}
+
+// moveInto atomically moves a file into dir without overriding existing files
+func moveInto(path, dir string) error {
+	target := filepath.Join(dir, filepath.Base(path))
+	if _, err := os.Stat(target); err == nil {
+		target = filepath.Join(dir, fmt.Sprintf("%s.%d", filepath.Base(path), time.Now().UnixNano()))
+	}
+	return os.Rename(path, target)
+}
@@ -0,0 +1,42 @@
+//go:build linux
+
+package spool
+
+import (
+	"os"
+	"syscall"
+)
+
+// watch uses inotify to notify about files that were written to or moved into dir until stop gets closed
+func watch(dir string, stop <-chan struct{}) (<-chan struct{}, error) {
+	fd, err := syscall.InotifyInit1(syscall.IN_CLOEXEC | syscall.IN_NONBLOCK)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := syscall.InotifyAddWatch(fd, dir, syscall.IN_CLOSE_WRITE|syscall.IN_MOVED_TO); err != nil {
+		syscall.Close(fd)
+		return nil, err
+	}
+
+	// Wrapping the non-blocking descriptor makes reads use the runtime poller, so closing it unblocks the reader
+	inotify := os.NewFile(uintptr(fd), "inotify")
+	events := make(chan struct{}, 1)
+	go func() {
+		buf := make([]byte, 4096)
+		for {
+			if _, err := inotify.Read(buf); err != nil {
+				close(events)
+				return
+			}
+			select {
+			case events <- struct{}{}:
+			default:
+			}
+		}
+	}()
+	go func() {
+		<-stop
+		inotify.Close()
+	}()
+	return events, nil
+}
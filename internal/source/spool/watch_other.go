@@ -0,0 +1,10 @@
+//go:build !linux
+
+package spool
+
+import "errors"
+
+// watch is not supported on this platform, meaning the spool directory is only polled
+func watch(_ string, _ <-chan struct{}) (<-chan struct{}, error) {
+	return nil, errors.New("watching directories is not supported on this platform")
+}
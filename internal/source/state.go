@@ -0,0 +1,47 @@
+package source
+
+import (
+	"errors"
+	"github.com/rs/zerolog/log"
+	"github.com/skybi/nuntius/internal/file"
+	"github.com/skybi/nuntius/internal/set"
+	"os"
+)
+
+// LoadState loads the set of reports a source processed the previous time from a state file.
+// A corrupt state file, whether its checksum does not match or its contents can not be decoded, is discarded as the
+// reports are deduplicated by the feeders anyway.
+func LoadState(filepath string) (*set.HashSet[string], error) {
+	data, err := file.Read(filepath)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return set.NewHashSet[string](), nil
+		}
+		if errors.Is(err, file.ErrChecksumMismatch) {
+			return discardState(filepath, err), nil
+		}
+		return nil, err
+	}
+
+	reports, err := set.Deserialize[string](data)
+	if err != nil {
+		return discardState(filepath, err), nil
+	}
+	return reports, nil
+}
+
+// discardState logs that a corrupt state file is discarded and returns the empty state replacing it
+func discardState(filepath string, err error) *set.HashSet[string] {
+	log.Warn().Err(err).Str("file", filepath).Msg("discarding corrupt state file")
+	return set.NewHashSet[string]()
+}
+
+// SaveState saves the set of reports a source processed into a state file
+func SaveState(filepath string, reports *set.HashSet[string]) error {
+	data, err := set.Serialize(reports)
+	if err != nil {
+		return err
+	}
+
+	return file.WriteWithChecksum(filepath, data)
+}
@@ -0,0 +1,70 @@
+package source
+
+import (
+	"github.com/skybi/nuntius/internal/file"
+	"github.com/skybi/nuntius/internal/set"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestLoadState(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "cycle-state-00")
+	if state, err := LoadState(path); err != nil || state.Size() != 0 {
+		t.Fatalf("LoadState() of a missing file = %v, %v, want an empty state", state, err)
+	}
+
+	reports := set.NewHashSet[string]()
+	reports.Add("EDDF 181220Z 24012KT CAVOK 15/08 Q1013")
+	if err := SaveState(path, reports); err != nil {
+		t.Fatalf("SaveState() error = %v", err)
+	}
+	if state, err := LoadState(path); err != nil || !state.Contains("EDDF 181220Z 24012KT CAVOK 15/08 Q1013") || state.Size() != 1 {
+		t.Fatalf("LoadState() = %v, %v, want the saved state", state, err)
+	}
+}
+
+func TestLoadStateCorrupt(t *testing.T) {
+	tests := []struct {
+		name  string
+		write func(path string) error
+	}{
+		{
+			name: "checksum mismatch",
+			write: func(path string) error {
+				if err := file.WriteWithChecksum(path, []byte{0x80}); err != nil {
+					return err
+				}
+				data, err := os.ReadFile(path)
+				if err != nil {
+					return err
+				}
+				data[0] ^= 0x20
+				return os.WriteFile(path, data, 0640)
+			},
+		},
+		{
+			name: "undecodable with footer",
+			write: func(path string) error {
+				return file.WriteWithChecksum(path, []byte("not cbor"))
+			},
+		},
+		{
+			name: "undecodable legacy file",
+			write: func(path string) error {
+				return os.WriteFile(path, []byte{0xbf, 0x61}, 0640)
+			},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "cycle-state-00")
+			if err := test.write(path); err != nil {
+				t.Fatal(err)
+			}
+			if state, err := LoadState(path); err != nil || state.Size() != 0 {
+				t.Errorf("LoadState() = %v, %v, want an empty state", state, err)
+			}
+		})
+	}
+}
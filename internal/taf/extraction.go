@@ -0,0 +1,66 @@
+package taf
+
+import (
+	"bufio"
+	"errors"
+	"github.com/skybi/nuntius/internal/set"
+	"io"
+	"strings"
+)
+
+// Extract extracts and deduplicates the raw TAFs out of a NOAA cycle file.
+// Unlike METARs, a single TAF spans several lines with every line except the first one being indented. TAFs are
+// separated by empty or timestamp lines.
+func Extract(reader *bufio.Reader) (*set.HashSet[string], error) {
+	hashSet := set.NewHashSet[string]()
+	if reader.Size() == 0 {
+		return hashSet, nil
+	}
+
+	var current []string
+	flush := func() {
+		if len(current) > 0 {
+			hashSet.Add(strings.Join(current, " "))
+			current = nil
+		}
+	}
+
+	end := false
+	for !end {
+		line, isPrefix, err := reader.ReadLine()
+		if err != nil && !errors.Is(err, io.EOF) {
+			return nil, err
+		}
+		for isPrefix {
+			fragment, isAnotherPrefix, err := reader.ReadLine()
+			if err != nil && !errors.Is(err, io.EOF) {
+				return nil, err
+			}
+			line = append(line, fragment...)
+			isPrefix = isAnotherPrefix
+		}
+		end = err != nil && errors.Is(err, io.EOF)
+
+		trimmed := strings.TrimSpace(string(line))
+		if len(trimmed) == 0 {
+			flush()
+			continue
+		}
+
+		// Indented lines continue the current TAF
+		if line[0] == ' ' || line[0] == '\t' {
+			current = append(current, trimmed)
+			continue
+		}
+		flush()
+
+		// 47 = '/'
+		if len(line) > 4 && line[4] == 47 {
+			continue
+		}
+		current = append(current, trimmed)
+	}
+	flush()
+
+	return hashSet, nil
+}
@@ -0,0 +1,14 @@
+package taf
+
+import (
+	"github.com/skybi/nuntius/internal/feeder"
+	"github.com/skybi/nuntius/internal/sink"
+)
+
+// DataDir is the directory the TAF feeder persists its queue, deduplication index and dead letters into
+const DataDir = "./data/taf"
+
+// NewFeeder creates a new TAF feeder draining its queue into the given sink
+func NewFeeder(sink sink.Sink, options feeder.Options) *feeder.Feeder {
+	return feeder.New("taf", sink, Fix, DataDir, options)
+}
@@ -0,0 +1,12 @@
+package taf
+
+import "strings"
+
+// Fix applies fixes on a raw TAF that solve common problems experienced over time
+func Fix(raw string) string {
+	// Normalize weird characters (i.e. replacing them with ASCII ones)
+	raw = strings.ReplaceAll(raw, "–", "-")
+
+	// Remove stacked and non-ASCII spaces ('   ' -> ' ')
+	return strings.Join(strings.Fields(raw), " ")
+}
@@ -0,0 +1,53 @@
+package timestamp
+
+import (
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Parse determines the time a raw report was observed or issued at out of its first DDHHMMZ group.
+// As the group lacks the month and year, they are chosen so that the time is the latest one not after now plus a day
+// of tolerance for clock skew.
+func Parse(raw string, now time.Time) (time.Time, bool) {
+	for _, group := range strings.Fields(raw) {
+		if len(group) != 7 || group[6] != 'Z' || !isDigits(group[:6]) {
+			continue
+		}
+		value, err := strconv.Atoi(group[:6])
+		if err != nil {
+			continue
+		}
+		day, hour, minute := value/10000, value/100%100, value%100
+		if day < 1 || day > 31 || hour > 23 || minute > 59 {
+			continue
+		}
+		return resolve(day, hour, minute, now.UTC()), true
+	}
+	return time.Time{}, false
+}
+
+// resolve finds the latest time with the given day, hour and minute that is not more than a day ahead of now
+func resolve(day, hour, minute int, now time.Time) time.Time {
+	limit := now.Add(24 * time.Hour)
+	year, month, _ := now.Date()
+	month++
+	for i := 0; i < 14; i++ {
+		candidate := time.Date(year, month, day, hour, minute, 0, 0, time.UTC)
+		// Skip days not existing in the month (time.Date would normalize them into the next one)
+		if candidate.Day() == day && !candidate.After(limit) {
+			return candidate
+		}
+		month--
+	}
+	return time.Time{}
+}
+
+func isDigits(value string) bool {
+	for _, char := range value {
+		if char < '0' || char > '9' {
+			return false
+		}
+	}
+	return true
+}
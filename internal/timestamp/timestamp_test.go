@@ -0,0 +1,132 @@
+package timestamp
+
+import (
+	"testing"
+	"time"
+)
+
+func date(year int, month time.Month, day, hour, minute int) time.Time {
+	return time.Date(year, month, day, hour, minute, 0, 0, time.UTC)
+}
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		name string
+		raw  string
+		now  time.Time
+		want time.Time
+	}{
+		{
+			name: "same day",
+			raw:  "EDDF 181220Z 24012KT CAVOK 15/08 Q1013",
+			now:  date(2026, time.October, 18, 12, 30),
+			want: date(2026, time.October, 18, 12, 20),
+		},
+		{
+			name: "first group",
+			raw:  "EDDF 181100Z 1812/1918 24012KT BECMG 1900/1902 191200Z",
+			now:  date(2026, time.October, 18, 12, 30),
+			want: date(2026, time.October, 18, 11, 0),
+		},
+		{
+			name: "ahead within tolerance",
+			raw:  "EDDF 191000Z",
+			now:  date(2026, time.October, 18, 12, 30),
+			want: date(2026, time.October, 19, 10, 0),
+		},
+		{
+			name: "ahead by exactly the tolerance",
+			raw:  "EDDF 191230Z",
+			now:  date(2026, time.October, 18, 12, 30),
+			want: date(2026, time.October, 19, 12, 30),
+		},
+		{
+			name: "ahead beyond tolerance",
+			raw:  "EDDF 191231Z",
+			now:  date(2026, time.October, 18, 12, 30),
+			want: date(2026, time.September, 19, 12, 31),
+		},
+		{
+			name: "end of previous month",
+			raw:  "EDDF 302350Z",
+			now:  date(2026, time.October, 1, 0, 10),
+			want: date(2026, time.September, 30, 23, 50),
+		},
+		{
+			name: "day 31 after a 30-day month",
+			raw:  "EDDF 312350Z",
+			now:  date(2026, time.October, 1, 0, 10),
+			want: date(2026, time.August, 31, 23, 50),
+		},
+		{
+			name: "day 31 within a 31-day month",
+			raw:  "EDDF 311200Z",
+			now:  date(2026, time.October, 31, 12, 30),
+			want: date(2026, time.October, 31, 12, 0),
+		},
+		{
+			name: "day 31 before a 30-day month",
+			raw:  "EDDF 312350Z",
+			now:  date(2026, time.May, 1, 0, 10),
+			want: date(2026, time.March, 31, 23, 50),
+		},
+		{
+			name: "January 1 of the next year",
+			raw:  "EDDF 010005Z",
+			now:  date(2026, time.December, 31, 23, 50),
+			want: date(2027, time.January, 1, 0, 5),
+		},
+		{
+			name: "December 31 of the previous year",
+			raw:  "EDDF 312350Z",
+			now:  date(2027, time.January, 1, 0, 10),
+			want: date(2026, time.December, 31, 23, 50),
+		},
+		{
+			name: "February 29 in a leap year",
+			raw:  "EDDF 292350Z",
+			now:  date(2028, time.March, 1, 0, 10),
+			want: date(2028, time.February, 29, 23, 50),
+		},
+		{
+			name: "February 29 in a common year",
+			raw:  "EDDF 292350Z",
+			now:  date(2027, time.March, 1, 0, 10),
+			want: date(2027, time.January, 29, 23, 50),
+		},
+		{
+			name: "non-UTC now",
+			raw:  "EDDF 182330Z",
+			now:  time.Date(2026, time.October, 19, 1, 0, 0, 0, time.FixedZone("CEST", 2*60*60)),
+			want: date(2026, time.October, 18, 23, 30),
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got, ok := Parse(test.raw, test.now)
+			if !ok || !got.Equal(test.want) {
+				t.Errorf("Parse() = %v, %v, want %v", got, ok, test.want)
+			}
+		})
+	}
+}
+
+func TestParseInvalid(t *testing.T) {
+	now := date(2026, time.October, 18, 12, 30)
+	for _, raw := range []string{
+		"",
+		"EDDF 24012KT CAVOK",
+		"EDDF 18122Z",
+		"EDDF 1812200Z",
+		"EDDF 001220Z",
+		"EDDF 321220Z",
+		"EDDF 182420Z",
+		"EDDF 181260Z",
+		"EDDF +12345Z",
+		"EDDF 18122AZ",
+	} {
+		if got, ok := Parse(raw, now); ok {
+			t.Errorf("Parse(%q) = %v, want no time", raw, got)
+		}
+	}
+}